@@ -0,0 +1,138 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/telemaco019/kubesafe/internal/core"
+	"github.com/telemaco019/kubesafe/internal/repositories"
+	"github.com/telemaco019/kubesafe/internal/utils"
+)
+
+// auditor records in the audit log the decision taken on a protected command
+type auditor struct {
+	log    *repositories.FileAuditLog
+	record core.AuditRecord
+}
+
+func newAuditor(
+	log *repositories.FileAuditLog,
+	cmd string,
+	args *utils.ParsedArgs,
+	rawArgs []string,
+	namespacedContext *utils.NamespacedContext,
+	safeContext string,
+	rule *core.Rule,
+) *auditor {
+	host, _ := os.Hostname()
+	return &auditor{
+		log: log,
+		record: core.AuditRecord{
+			User:        utils.CurrentUser(),
+			Host:        host,
+			Tool:        args.Tool,
+			Command:     cmd,
+			Args:        rawArgs,
+			Context:     namespacedContext.Context,
+			SafeContext: safeContext,
+			Namespace:   namespacedContext.Namespace,
+			Cluster:     namespacedContext.Server,
+			Rule:        rule.String(),
+		},
+	}
+}
+
+// Record appends the decision to the audit log. The exit code is nil if the command did not run.
+// Failing to write the audit log only shows a warning, so that it doesn't get in the way of the user.
+func (a *auditor) Record(decision string, reason string, prompted bool, exitCode *int) {
+	if a.log == nil {
+		return
+	}
+	record := a.record
+	record.Time = time.Now()
+	record.Decision = decision
+	record.Reason = reason
+	record.Prompted = prompted
+	record.ExitCode = exitCode
+	if err := a.log.Append(record); err != nil {
+		_ = utils.PrintWarning(fmt.Sprintf("[WARNING] Could not write the audit log: %v", err))
+	}
+}
+
+// getEnabledAuditLog returns the audit log, or an error if it is disabled in the settings
+func getEnabledAuditLog(repo *repositories.FileSystemRepository, settings *core.Settings) (*repositories.FileAuditLog, error) {
+	auditLog, err := repo.GetAuditLog(settings)
+	if err != nil {
+		return nil, err
+	}
+	if auditLog == nil {
+		return nil, fmt.Errorf("the audit log is disabled")
+	}
+	return auditLog, nil
+}
+
+func newVerifyAuditCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Verify that the audit log was not tampered with",
+		Long: "Verify that the records of the audit log were not edited, removed or reordered " +
+			"after being written, by checking the hash chain of the records.",
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := repositories.NewFileSystemRepository()
+			if err != nil {
+				return err
+			}
+			settings, err := repo.LoadSettings()
+			if err != nil {
+				return err
+			}
+			auditLog, err := getEnabledAuditLog(repo, settings)
+			if err != nil {
+				return err
+			}
+			count, err := auditLog.Verify()
+			if err != nil {
+				return fmt.Errorf("audit log %q verification failed: %w", auditLog.Path(), err)
+			}
+			fmt.Printf("Audit log %q verified: %d records\n", auditLog.Path(), count)
+			return nil
+		},
+	}
+}
+
+func NewAuditCmd() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Manage the audit log of the decisions taken on protected commands",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				_ = cmd.Help()
+				os.Exit(1)
+			}
+		},
+	}
+
+	auditCmd.AddCommand(newVerifyAuditCmd())
+
+	return auditCmd
+}
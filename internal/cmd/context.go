@@ -19,6 +19,9 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/charmbracelet/huh"
 	"github.com/spf13/cobra"
@@ -29,30 +32,184 @@ import (
 )
 
 const (
-	FLAG_COMMANDS = "commands"
+	FLAG_COMMANDS           = "commands"
+	FLAG_TOOL               = "tool"
+	FLAG_NAMESPACES         = "namespaces"
+	FLAG_EXCLUDE_NAMESPACES = "exclude-namespaces"
+	FLAG_MODE               = "mode"
+	FLAG_SEVERITY           = "severity"
 )
 
-func selectProtectedCommands(cmd *cobra.Command) ([]string, error) {
+// Tools for which kubesafe offers a default set of protected and allowed commands
+var defaultToolCommands = []struct {
+	tool      string
+	protected []string
+	allowed   []string
+}{
+	{tool: "kubectl", protected: core.DEFAULT_KUBECTL_PROTECTED_COMMANDS, allowed: core.DEFAULT_KUBECTL_ALLOWED_COMMANDS},
+	{tool: "helm", protected: core.DEFAULT_HELM_PROTECTED_COMMANDS, allowed: core.DEFAULT_HELM_ALLOWED_COMMANDS},
+}
+
+type toolCommand struct {
+	tool    string
+	command string
+}
+
+type modeOption struct {
+	mode        string
+	description string
+}
+
+func (o modeOption) String() string {
+	return fmt.Sprintf("%s: %s", o.mode, o.description)
+}
+
+func selectMode(cmd *cobra.Command) (string, error) {
+	// If user passed the mode as flag, return it
+	if cmd.Flags().Changed(FLAG_MODE) {
+		mode, err := cmd.Flags().GetString(FLAG_MODE)
+		if err != nil {
+			return "", err
+		}
+		if mode != core.MODE_DENYLIST && mode != core.MODE_ALLOWLIST {
+			return "", fmt.Errorf("invalid mode %q, must be either %q or %q", mode, core.MODE_DENYLIST, core.MODE_ALLOWLIST)
+		}
+		return mode, nil
+	}
+	// Commands passed as flag are protected commands
+	if cmd.Flags().Changed(FLAG_COMMANDS) {
+		return core.MODE_DENYLIST, nil
+	}
+	// Otherwise, let the user interactively select the mode
+	selected, err := utils.SelectItem(
+		[]modeOption{
+			{mode: core.MODE_DENYLIST, description: "protect only the selected commands"},
+			{mode: core.MODE_ALLOWLIST, description: "protect every command except the selected ones"},
+		},
+		"Select protection mode",
+	)
+	if err != nil {
+		return "", err
+	}
+	return selected.mode, nil
+}
+
+type severityOption struct {
+	severity    string
+	description string
+}
+
+func (o severityOption) String() string {
+	return fmt.Sprintf("%s: %s", o.severity, o.description)
+}
+
+func selectSeverity(cmd *cobra.Command) (string, error) {
+	// If user passed the severity as flag, return it
+	if cmd.Flags().Changed(FLAG_SEVERITY) {
+		severity, err := cmd.Flags().GetString(FLAG_SEVERITY)
+		if err != nil {
+			return "", err
+		}
+		if !slices.Contains(core.SEVERITIES, severity) {
+			return "", fmt.Errorf("unknown severity %q, must be one of %s", severity, strings.Join(core.SEVERITIES, ", "))
+		}
+		return severity, nil
+	}
+	// If the context is added non-interactively, use the default severity
+	if cmd.Flags().Changed(FLAG_COMMANDS) {
+		return core.SEVERITY_CONFIRM, nil
+	}
+	// Otherwise, let the user interactively select the severity
+	selected, err := utils.SelectItem(
+		[]severityOption{
+			{severity: core.SEVERITY_CONFIRM, description: "ask for a y/n confirmation"},
+			{severity: core.SEVERITY_TYPED_CONFIRM, description: "ask to type the context name"},
+			{severity: core.SEVERITY_WARN, description: "print a warning and run the command"},
+			{severity: core.SEVERITY_DENY, description: "never run the command"},
+		},
+		"Select what happens when running a protected command",
+	)
+	if err != nil {
+		return "", err
+	}
+	return selected.severity, nil
+}
+
+// selectCommands returns the commands applied to any tool and the commands of
+// each specific tool. Depending on the mode, these are either the protected or
+// the allowed commands.
+func selectCommands(cmd *cobra.Command, mode string) ([]string, map[string][]string, error) {
 	// If user passed the commands as flag, return them
 	if cmd.Flags().Changed(FLAG_COMMANDS) {
 		commands, err := cmd.Flags().GetStringSlice(FLAG_COMMANDS)
-		return commands, err
+		if err != nil {
+			return nil, nil, err
+		}
+		tool, err := cmd.Flags().GetString(FLAG_TOOL)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tool == "" {
+			return commands, nil, nil
+		}
+		return nil, map[string][]string{tool: commands}, nil
 	}
 	// Otherwise, let the user interactively select the commands
-	var commands []string
-	multiSelect := huh.NewMultiSelect[string]().
-		Title("Select proteced commands").
-		Value(&commands)
-	options := make([]huh.Option[string], 0)
-	for _, command := range core.DEFAULT_KUBECTL_PROTECTED_COMMANDS {
-		options = append(options, huh.NewOption(command, command).Selected(true))
+	title := "Select proteced commands"
+	if mode == core.MODE_ALLOWLIST {
+		title = "Select allowed commands"
+	}
+	var selected []toolCommand
+	multiSelect := huh.NewMultiSelect[toolCommand]().
+		Title(title).
+		Value(&selected)
+	options := make([]huh.Option[toolCommand], 0)
+	for _, d := range defaultToolCommands {
+		defaults := d.protected
+		if mode == core.MODE_ALLOWLIST {
+			defaults = d.allowed
+		}
+		for _, command := range defaults {
+			key := fmt.Sprintf("%s %s", d.tool, command)
+			options = append(options, huh.NewOption(key, toolCommand{d.tool, command}).Selected(true))
+		}
 	}
 	multiSelect.Options(options...)
 	err := multiSelect.Run()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	// Tools without a dedicated entry use all the selected commands
+	commands := make([]string, 0)
+	tools := make(map[string][]string)
+	for _, s := range selected {
+		tools[s.tool] = append(tools[s.tool], s.command)
+		if !slices.Contains(commands, s.command) {
+			commands = append(commands, s.command)
+		}
+	}
+	return commands, tools, nil
+}
+
+func newContextConf(contextName string, mode string, commands []string, toolCommands map[string][]string) core.ContextConf {
+	allowlist := mode == core.MODE_ALLOWLIST
+	contextConf := core.NewContextConf(contextName, commands)
+	if allowlist {
+		contextConf.Mode = mode
+		contextConf.ProtectedCommands = make([]string, 0)
+		contextConf.AllowedCommands = commands
+	}
+	for tool, commands := range toolCommands {
+		if contextConf.Tools == nil {
+			contextConf.Tools = make(map[string]core.ToolConf)
+		}
+		toolConf := core.ToolConf{ProtectedCommands: commands}
+		if allowlist {
+			toolConf = core.ToolConf{AllowedCommands: commands}
+		}
+		contextConf.Tools[tool] = toolConf
 	}
-	return commands, nil
+	return contextConf
 }
 
 func newAddContextCmd() *cobra.Command {
@@ -80,11 +237,27 @@ func newAddContextCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			protectedCommands, err := selectProtectedCommands(cmd)
+			mode, err := selectMode(cmd)
+			if err != nil {
+				return err
+			}
+			commands, toolCommands, err := selectCommands(cmd, mode)
+			if err != nil {
+				return err
+			}
+			contextConf := newContextConf(contextName, mode, commands, toolCommands)
+			contextConf.Severity, err = selectSeverity(cmd)
+			if err != nil {
+				return err
+			}
+			contextConf.Namespaces.Include, err = cmd.Flags().GetStringSlice(FLAG_NAMESPACES)
+			if err != nil {
+				return err
+			}
+			contextConf.Namespaces.Exclude, err = cmd.Flags().GetStringSlice(FLAG_EXCLUDE_NAMESPACES)
 			if err != nil {
 				return err
 			}
-			contextConf := core.NewContextConf(contextName, protectedCommands)
 			// Select actions
 			err = settings.AddContext(contextConf)
 			if err != nil {
@@ -101,11 +274,34 @@ func newAddContextCmd() *cobra.Command {
 	}
 
 	// Add flags
-	addContextCmd.Flags().StringSlice(FLAG_COMMANDS, nil, "Comma separated list of safe commands")
+	addContextCmd.Flags().
+		StringSlice(FLAG_COMMANDS, nil, "Comma separated list of safe commands (allowed commands in allowlist mode)")
+	addContextCmd.Flags().
+		String(FLAG_TOOL, "", "Tool (e.g. kubectl, helm) the commands passed with --commands apply to. If empty, they apply to any tool")
+	addContextCmd.Flags().
+		String(FLAG_MODE, core.MODE_DENYLIST, "Either \"denylist\" (protect only the given commands) or \"allowlist\" (protect every command except the given ones)")
+	addContextCmd.Flags().
+		String(FLAG_SEVERITY, core.SEVERITY_CONFIRM, fmt.Sprintf("What happens when running a protected command, one of %s", strings.Join(core.SEVERITIES, ", ")))
+	addContextCmd.Flags().
+		StringSlice(FLAG_NAMESPACES, nil, "Comma separated list of protected namespaces (names or regexes). If empty, all namespaces are protected")
+	addContextCmd.Flags().
+		StringSlice(FLAG_EXCLUDE_NAMESPACES, nil, "Comma separated list of namespaces (names or regexes) that are not protected")
 
 	return addContextCmd
 }
 
+func printToolConf(indent string, toolConf core.ToolConf) {
+	for _, command := range toolConf.ProtectedCommands {
+		fmt.Printf("%s- %s\n", indent, command)
+	}
+	for _, rule := range toolConf.Rules {
+		fmt.Printf("%s- %s\n", indent, rule)
+	}
+	for _, command := range toolConf.AllowedCommands {
+		fmt.Printf("%s+ %s\n", indent, command)
+	}
+}
+
 func newListContextsCmd() *cobra.Command {
 	removeContextCmd := &cobra.Command{
 		Use:          "list",
@@ -126,9 +322,26 @@ func newListContextsCmd() *cobra.Command {
 			}
 			// Print contexts
 			for _, context := range settings.Contexts {
-				fmt.Println(context.Name)
-				for _, command := range context.ProtectedCommands {
-					fmt.Printf("  - %s\n", command)
+				fmt.Printf("%s (%s)\n", context.Name, context.GetSeverity(nil))
+				if context.IsAllowlist() {
+					fmt.Printf("  mode: %s\n", context.Mode)
+				}
+				if !context.Namespaces.IsEmpty() {
+					fmt.Printf("  namespaces: %s\n", context.Namespaces)
+				}
+				printToolConf("  ", core.ToolConf{
+					ProtectedCommands: context.ProtectedCommands,
+					Rules:             context.Rules,
+					AllowedCommands:   context.AllowedCommands,
+				})
+				tools := make([]string, 0, len(context.Tools))
+				for tool := range context.Tools {
+					tools = append(tools, tool)
+				}
+				sort.Strings(tools)
+				for _, tool := range tools {
+					fmt.Printf("  %s:\n", tool)
+					printToolConf("    ", context.Tools[tool])
 				}
 			}
 			return nil
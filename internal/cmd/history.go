@@ -0,0 +1,249 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/telemaco019/kubesafe/internal/core"
+	"github.com/telemaco019/kubesafe/internal/repositories"
+	"github.com/telemaco019/kubesafe/internal/utils"
+)
+
+const (
+	FLAG_CONTEXT   = "context"
+	FLAG_NAMESPACE = "namespace"
+	FLAG_VERB      = "verb"
+	FLAG_DECISION  = "decision"
+	FLAG_SINCE     = "since"
+	FLAG_UNTIL     = "until"
+	FLAG_OUTPUT    = "output"
+	FLAG_LIMIT     = "limit"
+)
+
+const (
+	OUTPUT_TABLE = "table"
+	OUTPUT_JSON  = "json"
+	OUTPUT_CSV   = "csv"
+)
+
+var OUTPUTS = []string{
+	OUTPUT_TABLE,
+	OUTPUT_JSON,
+	OUTPUT_CSV,
+}
+
+const historyTimeLayout = "2006-01-02 15:04:05"
+
+func formatExitCode(exitCode *int) string {
+	if exitCode == nil {
+		return "-"
+	}
+	return strconv.Itoa(*exitCode)
+}
+
+func formatCommand(record core.AuditRecord) string {
+	return strings.Join(append([]string{record.Command}, record.Args...), " ")
+}
+
+func printHistoryTable(records []core.AuditRecord) error {
+	if len(records) == 0 {
+		fmt.Println("No records found.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "TIME\tUSER\tCONTEXT\tNAMESPACE\tDECISION\tEXIT CODE\tCOMMAND")
+	for _, r := range records {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Time.Local().Format(historyTimeLayout),
+			r.User,
+			r.Context,
+			r.Namespace,
+			r.Decision,
+			formatExitCode(r.ExitCode),
+			formatCommand(r),
+		)
+	}
+	return w.Flush()
+}
+
+func printHistoryJSON(records []core.AuditRecord) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+func printHistoryCSV(records []core.AuditRecord) error {
+	w := csv.NewWriter(os.Stdout)
+	_ = w.Write([]string{
+		"time", "user", "host", "context", "namespace", "cluster", "tool",
+		"verb", "command", "rule", "decision", "reason", "exitCode",
+	})
+	for _, r := range records {
+		exitCode := ""
+		if r.ExitCode != nil {
+			exitCode = strconv.Itoa(*r.ExitCode)
+		}
+		_ = w.Write([]string{
+			r.Time.Format(time.RFC3339),
+			r.User,
+			r.Host,
+			r.Context,
+			r.Namespace,
+			r.Cluster,
+			r.Tool,
+			r.Verb(),
+			formatCommand(r),
+			r.Rule,
+			r.Decision,
+			r.Reason,
+			exitCode,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func getHistoryFilter(cmd *cobra.Command) (core.AuditFilter, error) {
+	var res core.AuditFilter
+	var err error
+	if res.Context, err = cmd.Flags().GetString(FLAG_CONTEXT); err != nil {
+		return res, err
+	}
+	if res.Namespace, err = cmd.Flags().GetString(FLAG_NAMESPACE); err != nil {
+		return res, err
+	}
+	if res.Verb, err = cmd.Flags().GetString(FLAG_VERB); err != nil {
+		return res, err
+	}
+	if res.Decision, err = cmd.Flags().GetString(FLAG_DECISION); err != nil {
+		return res, err
+	}
+	if res.Since, res.Until, err = getTimeRange(cmd); err != nil {
+		return res, err
+	}
+	return res, res.Validate()
+}
+
+// getTimeRange returns the time range set with the --since and --until flags.
+// The bounds that are not set are zero.
+func getTimeRange(cmd *cobra.Command) (time.Time, time.Time, error) {
+	var since, until time.Time
+	var err error
+	now := time.Now()
+	if cmd.Flags().Changed(FLAG_SINCE) {
+		value, _ := cmd.Flags().GetString(FLAG_SINCE)
+		if since, err = utils.ParseTime(value, now); err != nil {
+			return since, until, err
+		}
+	}
+	if cmd.Flags().Changed(FLAG_UNTIL) {
+		value, _ := cmd.Flags().GetString(FLAG_UNTIL)
+		if until, err = utils.ParseTime(value, now); err != nil {
+			return since, until, err
+		}
+	}
+	return since, until, nil
+}
+
+func NewHistoryCmd() *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the history of the protected commands",
+		Long: "Show the decisions taken on protected commands, as recorded in the audit log, " +
+			"from the oldest to the newest.",
+		Example: `  # Show who deleted a deployment on prod-eu in the last 24 hours
+  kubesafe history --context prod-eu --verb delete --since 24h
+
+  # Export the commands canceled on the production contexts as CSV
+  kubesafe history --context 'prod-.*' --decision canceled -o csv`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, _ := cmd.Flags().GetString(FLAG_OUTPUT)
+			if !slices.Contains(OUTPUTS, output) {
+				return fmt.Errorf("unknown output %q, must be one of %s", output, strings.Join(OUTPUTS, ", "))
+			}
+			limit, _ := cmd.Flags().GetInt(FLAG_LIMIT)
+			filter, err := getHistoryFilter(cmd)
+			if err != nil {
+				return err
+			}
+
+			repo, err := repositories.NewFileSystemRepository()
+			if err != nil {
+				return err
+			}
+			settings, err := repo.LoadSettings()
+			if err != nil {
+				return err
+			}
+			auditLog, err := getEnabledAuditLog(repo, settings)
+			if err != nil {
+				return err
+			}
+			records, err := auditLog.Records()
+			if err != nil {
+				return err
+			}
+
+			records = filter.Filter(records)
+			// Keep the most recent records
+			if limit > 0 && len(records) > limit {
+				records = records[len(records)-limit:]
+			}
+
+			switch output {
+			case OUTPUT_JSON:
+				return printHistoryJSON(records)
+			case OUTPUT_CSV:
+				return printHistoryCSV(records)
+			default:
+				return printHistoryTable(records)
+			}
+		},
+	}
+
+	historyCmd.Flags().
+		String(FLAG_CONTEXT, "", "Show only the commands run on the given context (name or regex)")
+	historyCmd.Flags().
+		String(FLAG_NAMESPACE, "", "Show only the commands run on the given namespace (name or regex)")
+	historyCmd.Flags().
+		String(FLAG_VERB, "", "Show only the commands with the given verb (e.g. delete)")
+	historyCmd.Flags().
+		String(FLAG_DECISION, "", fmt.Sprintf("Show only the commands with the given decision, one of %s", strings.Join(core.DECISIONS, ", ")))
+	historyCmd.Flags().
+		String(FLAG_SINCE, "", "Show only the commands run after the given time, either a date (e.g. 2025-01-31) or a duration (e.g. 24h, 7d)")
+	historyCmd.Flags().
+		String(FLAG_UNTIL, "", "Show only the commands run before the given time, either a date (e.g. 2025-01-31) or a duration (e.g. 24h, 7d)")
+	historyCmd.Flags().
+		StringP(FLAG_OUTPUT, "o", OUTPUT_TABLE, fmt.Sprintf("Output format, one of %s", strings.Join(OUTPUTS, ", ")))
+	historyCmd.Flags().
+		Int(FLAG_LIMIT, 0, "Show only the given number of most recent commands. If 0, all the commands are shown")
+
+	return historyCmd
+}
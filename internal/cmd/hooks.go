@@ -0,0 +1,70 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/telemaco019/kubesafe/internal/core"
+	"github.com/telemaco019/kubesafe/internal/utils"
+)
+
+// runPreHooks invokes the provided hooks in order, showing their messages, and returns
+// their overall decision along with its reason: "deny" as soon as a hook denies the
+// command, "allow" if all the hooks allow it, and "ask" otherwise. Failing hooks are
+// considered as asking for confirmation. The decision is empty if there are no hooks.
+func runPreHooks(hooks []core.Hook, request core.PreHookRequest) (string, string) {
+	if len(hooks) == 0 {
+		return "", ""
+	}
+	decision := core.HOOK_ALLOW
+	for _, hook := range hooks {
+		name := filepath.Base(hook.Command)
+		response, err := hook.Decide(request)
+		if err != nil {
+			_ = utils.PrintWarning(fmt.Sprintf("[WARNING] %v", err))
+			decision = core.HOOK_ASK
+			continue
+		}
+		if response.Message != "" {
+			_ = utils.PrintWarning(fmt.Sprintf("[%s] %s", name, response.Message))
+		}
+		switch response.Decision {
+		case core.HOOK_DENY:
+			if response.Message != "" {
+				return core.HOOK_DENY, fmt.Sprintf("denied by hook %s: %s", name, response.Message)
+			}
+			return core.HOOK_DENY, fmt.Sprintf("denied by hook %s", name)
+		case core.HOOK_ASK:
+			decision = core.HOOK_ASK
+		}
+	}
+	if decision == core.HOOK_ALLOW {
+		return decision, "allowed by hooks"
+	}
+	return decision, ""
+}
+
+// runPostHooks invokes the provided hooks in order, showing a warning for the failing ones.
+func runPostHooks(hooks []core.Hook, request core.PostHookRequest) {
+	for _, hook := range hooks {
+		if _, err := hook.Run(request); err != nil {
+			_ = utils.PrintWarning(fmt.Sprintf("[WARNING] %v", err))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+const (
+	// EXIT_CODE_CANCELED is the exit code used when kubesafe cancels a protected command
+	EXIT_CODE_CANCELED = 1
+	// EXIT_CODE_CANNOT_EXECUTE is the exit code used when the wrapped command cannot be started
+	EXIT_CODE_CANNOT_EXECUTE = 126
+	// EXIT_CODE_NOT_FOUND is the exit code used when the wrapped command cannot be found
+	EXIT_CODE_NOT_FOUND = 127
+)
+
+// ExitError is returned when kubesafe has to exit with a specific code,
+// e.g. because the wrapped command exited with a non-zero status.
+type ExitError struct {
+	Code int
+	// Err is the cause of the failure, if kubesafe has something to report.
+	// It is nil if the wrapped command ran and reported its own errors.
+	Err error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code kubesafe should exit with after the provided error.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return 1
+}
+
+// PrintError prints the provided error like cobra does, unless the error
+// comes from the wrapped command, which already reported it.
+func PrintError(err error) {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) && exitErr.Err == nil {
+		return
+	}
+	_, _ = fmt.Fprintln(os.Stderr, "Error:", err)
+}
+
+// runCmd runs the wrapped command, forwarding the signals received by kubesafe,
+// and returns an ExitError if the command exits with a non-zero status.
+// If replace is true, kubesafe is replaced by the wrapped command when possible.
+func runCmd(cmd string, args []string, stdin io.Reader, replace bool) error {
+	path, err := exec.LookPath(cmd)
+	if err != nil {
+		return &ExitError{Code: EXIT_CODE_NOT_FOUND, Err: err}
+	}
+	// Stdin can be replayed only while kubesafe is running
+	if replace && stdin == os.Stdin {
+		err = execProcess(path, cmd, args)
+		if !errors.Is(err, errors.ErrUnsupported) {
+			return &ExitError{Code: EXIT_CODE_CANNOT_EXECUTE, Err: err}
+		}
+	}
+
+	execCommand := exec.Command(path, args...)
+	execCommand.Args[0] = cmd
+	execCommand.Stdout = os.Stdout
+	execCommand.Stderr = os.Stderr
+	execCommand.Stdin = stdin
+	if err = execCommand.Start(); err != nil {
+		return &ExitError{Code: EXIT_CODE_CANNOT_EXECUTE, Err: err}
+	}
+	stop := forwardSignals(execCommand.Process)
+	err = execCommand.Wait()
+	stop()
+
+	if code := exitCode(execCommand.ProcessState); code != 0 {
+		return &ExitError{Code: code}
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "No error", err: nil, want: 0},
+		{name: "Exit error", err: &ExitError{Code: 3}, want: 3},
+		{name: "Wrapped exit error", err: fmt.Errorf("failed: %w", &ExitError{Code: 130}), want: 130},
+		{name: "Other error", err: errors.New("failed"), want: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ExitCode(tc.err))
+		})
+	}
+}
+
+func TestPrintError(t *testing.T) {
+	// captureStderr returns what the provided function writes to stderr
+	captureStderr := func(t *testing.T, f func()) string {
+		r, w, err := os.Pipe()
+		assert.NoError(t, err)
+		stderr := os.Stderr
+		os.Stderr = w
+		defer func() { os.Stderr = stderr }()
+		f()
+		assert.NoError(t, w.Close())
+		out, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		return string(out)
+	}
+
+	testCases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "Error", err: errors.New("failed"), want: "Error: failed\n"},
+		{name: "Exit error with cause", err: &ExitError{Code: 1, Err: errors.New("denied")}, want: "Error: denied\n"},
+		{name: "Exit error of the wrapped command", err: &ExitError{Code: 3}, want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, captureStderr(t, func() { PrintError(tc.err) }))
+		})
+	}
+}
+
+func TestRunCmd_NotFound(t *testing.T) {
+	err := runCmd("kubesafe-unexisting-command", nil, os.Stdin, false)
+	assert.Equal(t, EXIT_CODE_NOT_FOUND, ExitCode(err))
+	assert.Error(t, err.(*ExitError).Err)
+}
@@ -0,0 +1,107 @@
+//go:build !windows
+
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Signals forwarded to the wrapped command
+var forwardedSignals = []os.Signal{
+	unix.SIGINT,
+	unix.SIGTERM,
+	unix.SIGHUP,
+	unix.SIGQUIT,
+	unix.SIGWINCH,
+	unix.SIGUSR1,
+	unix.SIGUSR2,
+}
+
+// Signals sent by the terminal to its whole foreground process group. The wrapped
+// command shares the process group of kubesafe, so it already receives them.
+var terminalSignals = []os.Signal{
+	unix.SIGINT,
+	unix.SIGQUIT,
+	unix.SIGWINCH,
+}
+
+// execProcess replaces the kubesafe process with the wrapped command.
+// It returns only if the command cannot be executed.
+func execProcess(path string, cmd string, args []string) error {
+	return syscall.Exec(path, append([]string{cmd}, args...), os.Environ())
+}
+
+// forwardSignals forwards the signals received by kubesafe to the provided
+// process, until the returned function is called.
+func forwardSignals(process *os.Process) func() {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		tty = nil
+	}
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, forwardedSignals...)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				// Don't deliver twice the signals coming from the terminal
+				if slices.Contains(terminalSignals, sig) && isForeground(tty) {
+					continue
+				}
+				_ = process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+		if tty != nil {
+			_ = tty.Close()
+		}
+	}
+}
+
+// isForeground returns true if kubesafe belongs to the foreground process group of the terminal.
+func isForeground(tty *os.File) bool {
+	if tty == nil {
+		return false
+	}
+	pgrp, err := unix.IoctlGetInt(int(tty.Fd()), unix.TIOCGPGRP)
+	if err != nil {
+		return false
+	}
+	return pgrp == unix.Getpgrp()
+}
+
+// exitCode returns the exit code of the provided process, following the
+// shell convention of 128 + the signal number for killed processes.
+func exitCode(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
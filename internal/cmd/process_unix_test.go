@@ -0,0 +1,45 @@
+//go:build !windows
+
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCmd(t *testing.T) {
+	testCases := []struct {
+		name   string
+		script string
+		want   int
+	}{
+		{name: "Success", script: "exit 0", want: 0},
+		{name: "Exit status is propagated", script: "exit 3", want: 3},
+		{name: "Killed by a signal", script: "kill -TERM $$", want: 128 + 15},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := runCmd("sh", []string{"-c", tc.script}, os.Stdin, false)
+			assert.Equal(t, tc.want, ExitCode(err))
+		})
+	}
+}
@@ -0,0 +1,46 @@
+//go:build windows
+
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+)
+
+// execProcess is not supported on Windows, where the wrapped command always runs as a child process.
+func execProcess(path string, cmd string, args []string) error {
+	return errors.ErrUnsupported
+}
+
+// forwardSignals keeps kubesafe running until the returned function is called.
+// Console interrupts are delivered by Windows to the wrapped command as well, so
+// kubesafe just waits for it to exit.
+func forwardSignals(process *os.Process) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	return func() {
+		signal.Stop(signals)
+	}
+}
+
+// exitCode returns the exit code of the provided process.
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
@@ -18,32 +18,68 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"github.com/telemaco019/kubesafe/internal/core"
 	"github.com/telemaco019/kubesafe/internal/repositories"
 	"github.com/telemaco019/kubesafe/internal/utils"
 )
 
 const (
 	FLAG_NO_INTERACTIVE = "no-interactive"
+	FLAG_EXEC           = "exec"
 )
 
-func runCmd(cmd string, args []string) {
-	execCommand := exec.Command(cmd, args...)
+// RANDOM_CHALLENGE_LENGTH is the length of the words generated for the random challenge
+const RANDOM_CHALLENGE_LENGTH = 6
 
-	var output bytes.Buffer
-	execCommand.Stdout = &output
-	execCommand.Stderr = &output
+// confirm asks the user for the confirmation required by the provided rule
+func confirm(contextConf *core.ContextConf, rule *core.Rule, context string, args *utils.ParsedArgs) (bool, error) {
+	message := fmt.Sprintf("[WARNING] Running a protected command on safe context %q.", context)
+	challenge, retries := contextConf.GetChallenge(rule)
+	switch challenge {
+	case core.CHALLENGE_CONTEXT:
+		return utils.ConfirmChallenge(message, context, retries)
+	case core.CHALLENGE_RESOURCE:
+		return utils.ConfirmChallenge(message, resourceChallenge(rule, context, args), retries)
+	case core.CHALLENGE_RANDOM:
+		return utils.ConfirmChallenge(message, utils.RandomWord(RANDOM_CHALLENGE_LENGTH), retries)
+	}
+	return utils.Confirm(message + " Are you sure?")
+}
 
-	execCommand.Stdout = os.Stdout
-	execCommand.Stderr = os.Stderr
-	execCommand.Stdin = os.Stdin
+// resourceChallenge returns the names of the resources targeted by the command,
+// or the context name if the command doesn't target any named resource.
+func resourceChallenge(rule *core.Rule, context string, args *utils.ParsedArgs) string {
+	names := make([]string, 0)
+	for _, ref := range args.Resources(len(strings.Fields(rule.Command))) {
+		if ref.Name != "" && !slices.Contains(names, ref.Name) {
+			names = append(names, ref.Name)
+		}
+	}
+	if len(names) == 0 {
+		return context
+	}
+	return strings.Join(names, " ")
+}
 
-	_ = execCommand.Run()
+// describeManifests returns a description of the objects affected by the command
+func describeManifests(objects []utils.ManifestObject) string {
+	var sb strings.Builder
+	sb.WriteString("The command affects the following objects:")
+	for _, object := range objects {
+		sb.WriteString("\n  - ")
+		sb.WriteString(object.String())
+	}
+	return sb.String()
 }
 
 type ParsedCommand struct {
@@ -51,7 +87,7 @@ type ParsedCommand struct {
 	WrappedArgs []string
 }
 
-func parseCommand(cmd *cobra.Command, args []string) ParsedCommand {
+func parseCommand(cmd *cobra.Command, args []string) (ParsedCommand, error) {
 	_ = cmd.Flags().Parse(args)
 	kubesafeFlags := make(map[string]struct{})
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
@@ -65,10 +101,13 @@ func parseCommand(cmd *cobra.Command, args []string) ParsedCommand {
 		}
 	}
 
+	if len(argsWithoutKubesafeFlags) == 0 {
+		return ParsedCommand{}, fmt.Errorf("missing command to run")
+	}
 	return ParsedCommand{
 		WrappedCmd:  argsWithoutKubesafeFlags[0],
 		WrappedArgs: argsWithoutKubesafeFlags[1:],
-	}
+	}, nil
 }
 
 func NewRootCmd() *cobra.Command {
@@ -78,6 +117,8 @@ func NewRootCmd() *cobra.Command {
 		Args:               cobra.ArbitraryArgs,
 		Short:              "", // TODO
 		SilenceUsage:       true,
+		// Errors are printed by main, except the ones of the wrapped command
+		SilenceErrors: true,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			if len(args) < 2 {
 				showHelp(cmd, args)
@@ -85,11 +126,16 @@ func NewRootCmd() *cobra.Command {
 			}
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			parsedCmd := parseCommand(cmd, args)
+			parsedCmd, err := parseCommand(cmd, args)
+			if err != nil {
+				_ = cmd.Help()
+				return err
+			}
 			wrappedCmd := parsedCmd.WrappedCmd
 			wrappedArgs := parsedCmd.WrappedArgs
+			parsedArgs := utils.ParseArgs(wrappedCmd, wrappedArgs)
 
-			namespacedContext, err := utils.GetNamespacedContext(args)
+			namespacedContext, err := utils.GetNamespacedContext(parsedArgs)
 			if err != nil {
 				return err
 			}
@@ -101,63 +147,199 @@ func NewRootCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			// Stdin is read only if it contains manifests to inspect, and it is replayed to the wrapped command
+			var stdin io.Reader = os.Stdin
+			replace, _ := cmd.Flags().GetBool(FLAG_EXEC)
+			run := func() error {
+				return runCmd(wrappedCmd, wrappedArgs, stdin, replace)
+			}
 			// Check if the context is included in the safe contexts
 			contextConf, ok := settings.GetContextConf(namespacedContext.Context)
 			if !ok {
-				runCmd(wrappedCmd, wrappedArgs)
-				return nil
+				return run()
 			}
-			// If no args then we don't need to check if the command is safe
-			if len(wrappedArgs) == 0 {
-				runCmd(wrappedCmd, wrappedArgs)
-				return nil
+			// If no subcommand then we don't need to check if the command is safe
+			if parsedArgs.Verb() == "" {
+				return run()
+			}
+			// Inspect the manifests passed to the command, so that rules apply to their content
+			if parsedArgs.HasManifests() {
+				var stdinContent []byte
+				if parsedArgs.ReadsStdin() {
+					stdinContent, err = io.ReadAll(os.Stdin)
+					if err != nil {
+						return err
+					}
+					stdin = bytes.NewReader(stdinContent)
+				}
+				manifests, err := parsedArgs.LoadManifests(stdinContent)
+				if err != nil {
+					_ = utils.PrintWarning(fmt.Sprintf("[WARNING] Could not inspect manifests: %v", err))
+				}
+				parsedArgs.Manifests = manifests
+				// Rules and namespace filters fail closed on the manifests that could not be inspected
+				parsedArgs.ManifestsIncomplete = err != nil || parsedArgs.HasRemoteManifests()
+			}
+			// If the namespace is not protected, then just run the command
+			if !contextConf.IsProtectedNamespace(namespacedContext, parsedArgs) {
+				return run()
 			}
 			// If the command is safe, then just run it
-			if !contextConf.IsProtected(wrappedArgs[0]) {
-				runCmd(wrappedCmd, wrappedArgs)
-				return nil
+			rule, ok := contextConf.Match(parsedArgs, core.NewEnvironment(namespacedContext))
+			if !ok {
+				return run()
 			}
-			// If no-interactive mode, just abort
-			noInteractive, _ := cmd.Flags().GetBool(FLAG_NO_INTERACTIVE)
-			if noInteractive {
+			auditLog, err := repo.GetAuditLog(settings)
+			if err != nil {
+				return err
+			}
+			audit := newAuditor(auditLog, wrappedCmd, parsedArgs, wrappedArgs, namespacedContext, contextConf.Name, rule)
+			// Set once the user has been asked to confirm the command
+			prompted := false
+			recordStats := func(decision string) error {
+				return repo.UpdateStats(settings, func(stats *core.Stats) {
+					stats.Get(contextConf.Name).Record(parsedArgs.Tool, parsedArgs.Verb(), decision, prompted, time.Now())
+				})
+			}
+			// Commands allowed to run are recorded in the audit log along with their exit code,
+			// then the post-execution hooks are invoked. The command replaces kubesafe only
+			// if there is nothing left to do once it exits.
+			execute := func(decision, reason, approval string, postHooks []core.Hook) error {
+				if err := recordStats(decision); err != nil {
+					_ = utils.PrintWarning(fmt.Sprintf("[WARNING] Could not update the stats: %v", err))
+				}
+				if replace && stdin == os.Stdin && len(postHooks) == 0 {
+					audit.Record(decision, reason, prompted, nil)
+					return run()
+				}
+				startedAt := time.Now()
+				err := runCmd(wrappedCmd, wrappedArgs, stdin, false)
+				exitCode := ExitCode(err)
+				audit.Record(decision, reason, prompted, &exitCode)
+				runPostHooks(postHooks, core.PostHookRequest{
+					Tool:          parsedArgs.Tool,
+					Command:       wrappedCmd,
+					Args:          wrappedArgs,
+					Context:       namespacedContext.Context,
+					Namespace:     namespacedContext.Namespace,
+					AllNamespaces: namespacedContext.AllNamespaces,
+					Rule:          rule.String(),
+					User:          utils.CurrentUser(),
+					Approval:      approval,
+					StartedAt:     startedAt,
+					DurationMs:    time.Since(startedAt).Milliseconds(),
+					ExitCode:      exitCode,
+				})
+				return err
+			}
+			// Canceled commands are recorded in the audit log and in the stats,
+			// and make kubesafe exit with a non-zero code
+			cancel := func(decision, reason string, cause error) error {
+				audit.Record(decision, reason, prompted, nil)
+				if err := recordStats(decision); err != nil {
+					return err
+				}
+				return &ExitError{Code: EXIT_CODE_CANCELED, Err: cause}
+			}
+			// Dry-run and preview commands don't change anything, so they can just run
+			if !contextConf.ProtectDryRun && parsedArgs.IsDryRun() {
+				return execute(core.DECISION_ALLOWED, "dry-run", core.APPROVAL_NONE, nil)
+			}
+			if len(parsedArgs.Manifests) > 0 {
+				if err = utils.PrintWarning(describeManifests(parsedArgs.Manifests)); err != nil {
+					return err
+				}
+			}
+			postHooks := contextConf.GetPostHooks(rule)
+			severity := contextConf.GetSeverity(rule)
+			if severity == core.SEVERITY_DENY {
+				return cancel(
+					core.DECISION_DENIED,
+					"severity deny",
+					fmt.Errorf("running %q is denied on safe context %q", rule.Command, namespacedContext.Context),
+				)
+			}
+			// Let the hooks decide whether the command can run, or the user has to confirm it
+			hooks := append(slices.Clone(settings.PreHooks), contextConf.PreHooks...)
+			decision, reason := runPreHooks(hooks, core.PreHookRequest{
+				Tool:          parsedArgs.Tool,
+				Command:       wrappedCmd,
+				Args:          wrappedArgs,
+				Context:       namespacedContext.Context,
+				Namespace:     namespacedContext.Namespace,
+				AllNamespaces: namespacedContext.AllNamespaces,
+				Rule:          rule.String(),
+				Severity:      severity,
+			})
+			switch decision {
+			case core.HOOK_DENY:
+				return cancel(
+					core.DECISION_DENIED,
+					reason,
+					fmt.Errorf("running %q on safe context %q was denied by a hook", rule.Command, namespacedContext.Context),
+				)
+			case core.HOOK_ALLOW:
+				return execute(core.DECISION_APPROVED, reason, core.APPROVAL_HOOK, postHooks)
+			case core.HOOK_ASK:
+				if severity == core.SEVERITY_WARN {
+					severity = core.SEVERITY_CONFIRM
+				}
+			}
+			if severity == core.SEVERITY_WARN {
 				err = utils.PrintWarning(
 					fmt.Sprintf(
 						"[WARNING] Running a protected command on safe context %q.",
 						namespacedContext.Context,
 					),
 				)
-				fmt.Println("Canceled")
 				if err != nil {
 					return err
 				}
-				contextConf.Stats.CanceledCount += 1
-				return repo.SaveSettings(*settings)
+				return execute(core.DECISION_ALLOWED, "severity warn", core.APPROVAL_NONE, postHooks)
+			}
+			// Ask for confirmation, unless in no-interactive mode.
+			// If there is no terminal to ask, fall back to the no-interactive mode.
+			reason = "no-interactive mode"
+			noInteractive, _ := cmd.Flags().GetBool(FLAG_NO_INTERACTIVE)
+			if !noInteractive {
+				proceed, err := confirm(contextConf, rule, namespacedContext.Context, parsedArgs)
+				if err == nil {
+					prompted = true
+					if proceed {
+						return execute(core.DECISION_APPROVED, "confirmed by user", core.APPROVAL_USER, postHooks)
+					}
+					fmt.Println("Canceled")
+					return cancel(core.DECISION_CANCELED, "declined by user", nil)
+				}
+				if !errors.Is(err, utils.ErrNoTerminal) {
+					return err
+				}
+				reason = "no terminal available"
 			}
-			// Otherwise, ask for confirmation
-			proceed, err := utils.Confirm(
+			// In no-interactive mode, just abort
+			err = utils.PrintWarning(
 				fmt.Sprintf(
-					"[WARNING] Running a protected command on safe context %q. Are you sure?",
+					"[WARNING] Running a protected command on safe context %q.",
 					namespacedContext.Context,
 				),
 			)
+			fmt.Println("Canceled")
 			if err != nil {
 				return err
 			}
-			if proceed {
-				runCmd(wrappedCmd, wrappedArgs)
-				return nil
-			}
-			fmt.Println("Canceled")
-			contextConf.Stats.CanceledCount += 1
-			return repo.SaveSettings(*settings)
+			return cancel(core.DECISION_CANCELED, reason, nil)
 		},
 	}
 
 	// Add sub commands
 	rootCmd.AddCommand(NewContextCmd())
 	rootCmd.AddCommand(NewStatsCmd())
+	rootCmd.AddCommand(NewHistoryCmd())
+	rootCmd.AddCommand(NewAuditCmd())
 	rootCmd.Flags().
 		Bool(FLAG_NO_INTERACTIVE, false, "If set, kubesafe will directly prevent the execution on protected contexts without asking for confirmation")
+	rootCmd.Flags().
+		Bool(FLAG_EXEC, false, "If set, kubesafe replaces itself with the wrapped command once it is allowed to run (not supported on Windows)")
 	return rootCmd
 }
 
@@ -173,6 +355,9 @@ func showHelp(cmd *cobra.Command, args []string) {
 	// Forward to the wrapped command
 	wrappedCmd := args[0]
 	forwardedArgs := args[1:]
-	runCmd(wrappedCmd, forwardedArgs)
-	os.Exit(1)
+	err := runCmd(wrappedCmd, forwardedArgs, os.Stdin, false)
+	if err != nil {
+		PrintError(err)
+	}
+	os.Exit(ExitCode(err))
 }
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCommand(t *testing.T) {
+	testCases := []struct {
+		name    string
+		args    []string
+		want    ParsedCommand
+		wantErr bool
+	}{
+		{
+			name: "Command without kubesafe flags",
+			args: []string{"kubectl", "delete", "pod", "x"},
+			want: ParsedCommand{WrappedCmd: "kubectl", WrappedArgs: []string{"delete", "pod", "x"}},
+		},
+		{
+			name: "Kubesafe flags are removed",
+			args: []string{"--exec", "kubectl", "delete", "--no-interactive", "pod", "x"},
+			want: ParsedCommand{WrappedCmd: "kubectl", WrappedArgs: []string{"delete", "pod", "x"}},
+		},
+		{
+			name:    "Only kubesafe flags",
+			args:    []string{"--exec", "--no-interactive"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := parseCommand(NewRootCmd(), tc.args)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, res)
+		})
+	}
+}
@@ -25,11 +25,25 @@ import (
 )
 
 type ContextSelector struct {
-	settings          core.Settings
+	settings core.Settings
+	// availableContexts maps each context to the kubeconfig file it comes from
 	availableContexts map[string]string
 	userArgs          []string
 }
 
+// selectableContext is a context shown together with the kubeconfig file it comes from
+type selectableContext struct {
+	name   string
+	origin string
+}
+
+func (c selectableContext) String() string {
+	if c.origin == "" {
+		return c.name
+	}
+	return fmt.Sprintf("%s (%s)", c.name, c.origin)
+}
+
 func NewContextSelector(
 	settings core.Settings,
 	availableContexts map[string]string,
@@ -43,7 +57,6 @@ func NewContextSelector(
 }
 
 func (s *ContextSelector) SelectContext() (string, error) {
-	var contextName string
 	// If context is passed as arg, just validate it
 	if len(s.userArgs) > 0 {
 		if err := s.validateContext(s.userArgs[0]); err != nil {
@@ -52,21 +65,24 @@ func (s *ContextSelector) SelectContext() (string, error) {
 		return s.userArgs[0], nil
 	}
 	// Otherwise, let the user select a context
-	selectableContexts := make([]string, 0)
-	for _, context := range s.availableContexts {
+	selectableContexts := make([]selectableContext, 0)
+	for context, origin := range s.availableContexts {
 		if !s.settings.ContainsContext(context) {
-			selectableContexts = append(selectableContexts, context)
+			selectableContexts = append(selectableContexts, selectableContext{context, origin})
 		}
 	}
 	if len(selectableContexts) == 0 {
 		return "", fmt.Errorf("no contexts are available")
 	}
-	sort.Strings(selectableContexts) // sort for deterministic output
-	contextName, err := utils.SelectItem(selectableContexts, "Select a context to add: ")
+	// sort for deterministic output
+	sort.Slice(selectableContexts, func(i, j int) bool {
+		return selectableContexts[i].name < selectableContexts[j].name
+	})
+	context, err := utils.SelectItem(selectableContexts, "Select a context to add: ")
 	if err != nil {
 		return "", err
 	}
-	return contextName, nil
+	return context.name, nil
 }
 
 func (s *ContextSelector) validateContext(context string) error {
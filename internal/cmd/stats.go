@@ -20,47 +20,209 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/telemaco019/kubesafe/internal/core"
 	"github.com/telemaco019/kubesafe/internal/repositories"
 )
 
-func printStats(contexts []core.ContextConf) {
-	if len(contexts) == 0 {
-		fmt.Println("No contexts found.")
-		return
+const (
+	FLAG_BREAKDOWN = "breakdown"
+	FLAG_SORT_BY   = "sort-by"
+	FLAG_PERIOD    = "period"
+)
+
+const statsTimeLayout = "2006-01-02 15:04"
+
+// statsRow is a row of the stats table, either of a context or of a tool and verb of a context,
+// optionally in a period of time
+type statsRow struct {
+	Period  time.Time
+	Context string
+	Tool    string
+	Verb    string
+	core.CommandStats
+}
+
+type statsColumn struct {
+	name string
+	// less sorts the rows by the column. Counts and times are sorted in descending order.
+	less func(a, b statsRow) bool
+}
+
+var STATS_COLUMNS = []statsColumn{
+	{"period", func(a, b statsRow) bool { return a.Period.Before(b.Period) }},
+	{"context", func(a, b statsRow) bool { return a.Context < b.Context }},
+	{"tool", func(a, b statsRow) bool { return a.Tool < b.Tool }},
+	{"verb", func(a, b statsRow) bool { return a.Verb < b.Verb }},
+	{"prompted", func(a, b statsRow) bool { return a.PromptedCount > b.PromptedCount }},
+	{"confirmed", func(a, b statsRow) bool { return a.ConfirmedCount > b.ConfirmedCount }},
+	{"canceled", func(a, b statsRow) bool { return a.CanceledCount > b.CanceledCount }},
+	{"denied", func(a, b statsRow) bool { return a.DeniedCount > b.DeniedCount }},
+	{"allowed", func(a, b statsRow) bool { return a.AllowedCount > b.AllowedCount }},
+	{"first-seen", func(a, b statsRow) bool { return a.FirstSeen.After(b.FirstSeen) }},
+	{"last-seen", func(a, b statsRow) bool { return a.LastSeen.After(b.LastSeen) }},
+}
+
+func getStatsColumn(name string) (statsColumn, error) {
+	names := make([]string, 0, len(STATS_COLUMNS))
+	for _, c := range STATS_COLUMNS {
+		if c.name == name {
+			return c, nil
+		}
+		names = append(names, c.name)
 	}
+	return statsColumn{}, fmt.Errorf("unknown column %q, must be one of %s", name, strings.Join(names, ", "))
+}
 
-	// Find the longest context name
-	maxNameLen := len("Context")
+func getStatsRows(contexts []core.ContextConf, stats *core.Stats, breakdown bool) []statsRow {
+	rows := make([]statsRow, 0)
 	for _, c := range contexts {
-		if l := len(c.Name); l > maxNameLen {
-			maxNameLen = l
+		contextStats := stats.Get(c.Name)
+		if !breakdown {
+			rows = append(rows, statsRow{Context: c.Name, CommandStats: contextStats.CommandStats})
+			continue
+		}
+		for tool, verbs := range contextStats.Tools {
+			for verb, stats := range verbs {
+				rows = append(rows, statsRow{Context: c.Name, Tool: tool, Verb: verb, CommandStats: *stats})
+			}
 		}
 	}
+	return rows
+}
 
-	// Calculate padding and separator length
-	padding := 4
-	firstColumnWidth := maxNameLen + padding
-	separatorLength := firstColumnWidth + len("Canceled Commands")
+// getWindowedStatsRows aggregates the stats from the records of the audit log
+// in the provided time range, optionally bucketing them by period
+func getWindowedStatsRows(records []core.AuditRecord, since, until time.Time, breakdown bool, period string) []statsRow {
+	records = core.AuditFilter{Since: since, Until: until}.Filter(records)
+	rows := make([]statsRow, 0)
+	for key, stats := range core.AggregateStats(records, breakdown, period, time.Local) {
+		rows = append(rows, statsRow{
+			Period:       key.Period,
+			Context:      key.Context,
+			Tool:         key.Tool,
+			Verb:         key.Verb,
+			CommandStats: *stats,
+		})
+	}
+	return rows
+}
 
-	fmt.Printf("%-*s%s\n", firstColumnWidth, "Context", "Canceled Commands")
-	fmt.Println(strings.Repeat("-", separatorLength))
+func formatStatsTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(statsTimeLayout)
+}
 
-	for _, c := range contexts {
-		fmt.Printf("%-*s%d\n", firstColumnWidth, c.Name, c.Stats.CanceledCount)
+func printStats(rows []statsRow, breakdown bool, period string) error {
+	if len(rows) == 0 {
+		fmt.Println("No stats found.")
+		return nil
+	}
+
+	header := []string{}
+	if period == core.STATS_PERIOD_WEEK {
+		header = append(header, "Week")
+	} else if period != "" {
+		header = append(header, "Day")
+	}
+	header = append(header, "Context")
+	if breakdown {
+		header = append(header, "Tool", "Verb")
+	}
+	header = append(header, "Prompted", "Confirmed", "Canceled", "Denied", "Allowed", "First Seen", "Last Seen")
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 4, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, r := range rows {
+		fields := []string{}
+		if period != "" {
+			fields = append(fields, r.Period.Format(time.DateOnly))
+		}
+		fields = append(fields, r.Context)
+		if breakdown {
+			fields = append(fields, r.Tool, r.Verb)
+		}
+		fields = append(
+			fields,
+			fmt.Sprint(r.PromptedCount),
+			fmt.Sprint(r.ConfirmedCount),
+			fmt.Sprint(r.CanceledCount),
+			fmt.Sprint(r.DeniedCount),
+			fmt.Sprint(r.AllowedCount),
+			formatStatsTime(r.FirstSeen),
+			formatStatsTime(r.LastSeen),
+		)
+		fmt.Fprintln(w, strings.Join(fields, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
 	}
 
+	// Print the table with a separator below the header and at the end
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	separatorLength := 0
+	for _, l := range lines {
+		separatorLength = max(separatorLength, len(strings.TrimRight(l, " ")))
+	}
+	fmt.Println(lines[0])
 	fmt.Println(strings.Repeat("-", separatorLength))
+	for _, l := range lines[1:] {
+		fmt.Println(l)
+	}
+	fmt.Println(strings.Repeat("-", separatorLength))
+	return nil
 }
+
 func NewStatsCmd() *cobra.Command {
 	statsCommand := &cobra.Command{
-		Use:                   "stats",
-		Short:                 "Show Kubesafe statistics",
-		Args:                  cobra.NoArgs,
-		DisableFlagsInUseLine: true,
+		Use:   "stats",
+		Short: "Show Kubesafe statistics",
+		Long: "Show how many protected commands were prompted, confirmed, canceled, denied and allowed " +
+			"without confirmation (e.g. dry-runs) on each safe context.\n\n" +
+			"By default the stats are all-time. With --since, --until or --period, they are computed " +
+			"from the records of the audit log.",
+		Example: `  # Show the stats of the last week, broken down by tool and verb
+  kubesafe stats --since 7d --breakdown
+
+  # Show whether the commands canceled on prod are trending down, week by week
+  kubesafe stats --since 90d --period week`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			breakdown, _ := cmd.Flags().GetBool(FLAG_BREAKDOWN)
+			period, _ := cmd.Flags().GetString(FLAG_PERIOD)
+			if err := core.ValidateStatsPeriod(period); err != nil {
+				return err
+			}
+			since, until, err := getTimeRange(cmd)
+			if err != nil {
+				return err
+			}
+			if err = (core.AuditFilter{Since: since, Until: until}).Validate(); err != nil {
+				return err
+			}
+			windowed := period != "" || !since.IsZero() || !until.IsZero()
+			// Stats bucketed by period are shown in chronological order, unless sorted otherwise
+			sortBy, _ := cmd.Flags().GetString(FLAG_SORT_BY)
+			if period != "" && !cmd.Flags().Changed(FLAG_SORT_BY) {
+				sortBy = "period"
+			}
+			column, err := getStatsColumn(sortBy)
+			if err != nil {
+				return err
+			}
+			if !breakdown && (column.name == "tool" || column.name == "verb") {
+				return fmt.Errorf("sorting by %q requires the --%s flag", column.name, FLAG_BREAKDOWN)
+			}
+			if period == "" && column.name == "period" {
+				return fmt.Errorf("sorting by %q requires the --%s flag", column.name, FLAG_PERIOD)
+			}
+
 			repo, err := repositories.NewFileSystemRepository()
 			if err != nil {
 				return err
@@ -71,23 +233,63 @@ func NewStatsCmd() *cobra.Command {
 				return err
 			}
 
-			if len(settings.Contexts) == 0 {
-				fmt.Println("No contexts found.")
-				return nil
+			var rows []statsRow
+			if windowed {
+				auditLog, err := getEnabledAuditLog(repo, settings)
+				if err != nil {
+					return err
+				}
+				records, err := auditLog.Records()
+				if err != nil {
+					return err
+				}
+				rows = getWindowedStatsRows(records, since, until, breakdown, period)
+			} else {
+				if len(settings.Contexts) == 0 {
+					fmt.Println("No contexts found.")
+					return nil
+				}
+				stats, err := repo.LoadStats(settings)
+				if err != nil {
+					return err
+				}
+				rows = getStatsRows(settings.Contexts, stats, breakdown)
 			}
 
 			fmt.Println("\nKubesafe Context Statistics")
 			fmt.Println()
 
-			// Sort contexts by canceled count descending
-			sort.Slice(settings.Contexts, func(i, j int) bool {
-				return settings.Contexts[i].Stats.CanceledCount > settings.Contexts[j].Stats.CanceledCount
+			// Sort by the period, context, tool and verb first, so that ties are shown in a stable order
+			sort.SliceStable(rows, func(i, j int) bool {
+				a, b := rows[i], rows[j]
+				if !a.Period.Equal(b.Period) {
+					return a.Period.Before(b.Period)
+				}
+				if a.Context != b.Context {
+					return a.Context < b.Context
+				}
+				if a.Tool != b.Tool {
+					return a.Tool < b.Tool
+				}
+				return a.Verb < b.Verb
 			})
-			printStats(settings.Contexts)
-
-			return nil
+			sort.SliceStable(rows, func(i, j int) bool {
+				return column.less(rows[i], rows[j])
+			})
+			return printStats(rows, breakdown, period)
 		},
 	}
 
+	statsCommand.Flags().
+		Bool(FLAG_BREAKDOWN, false, "Break down the stats of each context by tool and verb")
+	statsCommand.Flags().
+		String(FLAG_SORT_BY, "canceled", "Column to sort the stats by, e.g. context, canceled or last-seen. Defaults to period with --period")
+	statsCommand.Flags().
+		String(FLAG_SINCE, "", "Show only the stats of the commands run after the given time, either a date (e.g. 2025-01-31) or a duration (e.g. 24h, 7d)")
+	statsCommand.Flags().
+		String(FLAG_UNTIL, "", "Show only the stats of the commands run before the given time, either a date (e.g. 2025-01-31) or a duration (e.g. 24h, 7d)")
+	statsCommand.Flags().
+		String(FLAG_PERIOD, "", fmt.Sprintf("Bucket the stats by period, one of %s", strings.Join(core.STATS_PERIODS, ", ")))
+
 	return statsCommand
 }
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// DECISION_ALLOWED means that the command ran without approval, e.g. with the "warn" severity or as a dry-run.
+	DECISION_ALLOWED = "allowed"
+	// DECISION_APPROVED means that the command ran after being approved by the user or by the hooks.
+	DECISION_APPROVED = "approved"
+	// DECISION_CANCELED means that the command did not run because it was not approved.
+	DECISION_CANCELED = "canceled"
+	// DECISION_DENIED means that the command did not run because it is denied by a rule or by a hook.
+	DECISION_DENIED = "denied"
+)
+
+var DECISIONS = []string{
+	DECISION_ALLOWED,
+	DECISION_APPROVED,
+	DECISION_CANCELED,
+	DECISION_DENIED,
+}
+
+// DEFAULT_AUDIT_FILE_MODE is the permissions of the audit log file, if not configured.
+const DEFAULT_AUDIT_FILE_MODE os.FileMode = 0600
+
+// AuditConf configures the audit log, where kubesafe records the decisions taken on protected commands.
+type AuditConf struct {
+	// Disabled turns off the audit log.
+	Disabled bool `yaml:"disabled,omitempty"`
+	// Path is the path of the audit log. If empty, the log is stored next to the settings file.
+	Path string `yaml:"path,omitempty"`
+	// FileMode is the permissions of the audit log file in octal notation, e.g. "0640".
+	// If empty, DEFAULT_AUDIT_FILE_MODE is used.
+	FileMode string `yaml:"fileMode,omitempty"`
+}
+
+// GetFileMode returns the permissions of the audit log file.
+func (c AuditConf) GetFileMode() (os.FileMode, error) {
+	if c.FileMode == "" {
+		return DEFAULT_AUDIT_FILE_MODE, nil
+	}
+	mode, err := strconv.ParseUint(c.FileMode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid audit file mode %q, must be in octal notation (e.g. \"0600\")", c.FileMode)
+	}
+	return os.FileMode(mode), nil
+}
+
+func (c AuditConf) validate() error {
+	_, err := c.GetFileMode()
+	return err
+}
+
+// AuditRecord is an entry of the audit log. Records are chained: each record
+// includes the hash of the previous one, so that edits, removals and reorderings
+// can be detected.
+type AuditRecord struct {
+	// Seq is the position of the record in the audit log, starting from 1.
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	// User is the user running kubesafe.
+	User string `json:"user"`
+	Host string `json:"host"`
+	// Tool is the name of the wrapped tool, e.g. "kubectl" or "helm".
+	Tool string `json:"tool"`
+	// Command is the wrapped command as invoked by the user.
+	Command string `json:"command"`
+	// Args are the arguments of the wrapped command.
+	Args    []string `json:"args"`
+	Context string   `json:"context"`
+	// SafeContext is the name of the safe context matching Context, which may be a regex.
+	SafeContext string `json:"safeContext,omitempty"`
+	Namespace   string `json:"namespace"`
+	// Cluster is the address of the cluster of the context.
+	Cluster string `json:"cluster,omitempty"`
+	// Rule is the protection rule matched by the command.
+	Rule string `json:"rule"`
+	// Decision is one of "allowed", "approved", "canceled" and "denied".
+	Decision string `json:"decision"`
+	// Reason explains the decision, e.g. "confirmed by user".
+	Reason string `json:"reason"`
+	// Prompted is true if the user was asked to confirm the command.
+	Prompted bool `json:"prompted,omitempty"`
+	// ExitCode is the exit code of the command. It is nil if the command
+	// did not run, or if kubesafe was replaced by the command.
+	ExitCode *int `json:"exitCode,omitempty"`
+	// PrevHash is the hash of the previous record, empty for the first record.
+	PrevHash string `json:"prevHash"`
+	// Hash is the hash of the record, including PrevHash. It is set by Seal.
+	Hash string `json:"hash,omitempty"`
+}
+
+// Suffix of the sealed records, followed by the hash and by the end of the JSON object
+const auditHashSuffix = `,"hash":"`
+
+func hashAuditContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Seal returns the line storing the record in the audit log, ending with the hash of its content.
+func (r AuditRecord) Seal() ([]byte, string, error) {
+	r.Hash = ""
+	content, err := json.Marshal(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("error marshalling audit record: %w", err)
+	}
+	hash := hashAuditContent(content)
+	line := append(content[:len(content)-1], auditHashSuffix+hash+`"}`...)
+	return line, hash, nil
+}
+
+// ParseAuditRecord parses a line of the audit log, returning an error if its
+// content doesn't match its hash.
+func ParseAuditRecord(line []byte) (AuditRecord, error) {
+	var res AuditRecord
+	if err := json.Unmarshal(line, &res); err != nil {
+		return res, fmt.Errorf("invalid record: %w", err)
+	}
+	i := bytes.LastIndex(line, []byte(auditHashSuffix))
+	if res.Hash == "" || i < 0 {
+		return res, fmt.Errorf("record is not sealed")
+	}
+	content := append(bytes.Clone(line[:i]), '}')
+	if hashAuditContent(content) != res.Hash || !bytes.Equal(line[i:], []byte(auditHashSuffix+res.Hash+`"}`)) {
+		return res, fmt.Errorf("record %d does not match its hash", res.Seq)
+	}
+	return res, nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseAuditRecord(t *testing.T) {
+	record := AuditRecord{Seq: 2, Context: "prod", Args: []string{"delete", "pod", "x"}, PrevHash: "abc"}
+	line, hash, err := record.Seal()
+	assert.NilError(t, err)
+
+	testCases := []struct {
+		name    string
+		line    []byte
+		wantErr string
+	}{
+		{
+			name: "Sealed record",
+			line: line,
+		},
+		{
+			name:    "Edited record",
+			line:    bytes.Replace(line, []byte(`"prod"`), []byte(`"dev"`), 1),
+			wantErr: "record 2 does not match its hash",
+		},
+		{
+			name:    "Edited previous hash",
+			line:    bytes.Replace(line, []byte(`"abc"`), []byte(`"abd"`), 1),
+			wantErr: "record 2 does not match its hash",
+		},
+		{
+			name:    "Record not sealed",
+			line:    []byte(`{"seq":2,"context":"prod"}`),
+			wantErr: "record is not sealed",
+		},
+		{
+			name:    "Invalid record",
+			line:    []byte(`{"seq":2`),
+			wantErr: "invalid record",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := ParseAuditRecord(tc.line)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, res.Hash, hash)
+			record.Hash = hash
+			assert.DeepEqual(t, res, record)
+		})
+	}
+}
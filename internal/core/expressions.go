@@ -0,0 +1,157 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"cel.dev/cel-go/cel"
+	"github.com/telemaco019/kubesafe/internal/utils"
+)
+
+// Environment describes where a command runs, for the expressions of the rules.
+type Environment struct {
+	Context       string
+	Namespace     string
+	AllNamespaces bool
+	// Cluster is the address of the cluster of the context.
+	Cluster string
+	// User is the user running kubesafe.
+	User string
+	// KubeUser is the name of the kubeconfig user of the context.
+	KubeUser string
+	Time     time.Time
+}
+
+func NewEnvironment(namespacedContext *utils.NamespacedContext) *Environment {
+	return &Environment{
+		Context:       namespacedContext.Context,
+		Namespace:     namespacedContext.Namespace,
+		AllNamespaces: namespacedContext.AllNamespaces,
+		Cluster:       namespacedContext.Server,
+		User:          utils.CurrentUser(),
+		KubeUser:      namespacedContext.AuthInfo,
+		Time:          time.Now(),
+	}
+}
+
+// The variables available to the expressions of the rules. The namespace is
+// available as "ns", since "namespace" is a reserved word in CEL.
+var expressionVariables = []cel.EnvOption{
+	cel.Variable("tool", cel.StringType),
+	cel.Variable("verb", cel.StringType),
+	cel.Variable("args", cel.ListType(cel.StringType)),
+	cel.Variable("resources", cel.ListType(cel.MapType(cel.StringType, cel.StringType))),
+	cel.Variable("flags", cel.MapType(cel.StringType, cel.StringType)),
+	cel.Variable("context", cel.StringType),
+	cel.Variable("ns", cel.StringType),
+	cel.Variable("allNamespaces", cel.BoolType),
+	cel.Variable("cluster", cel.StringType),
+	cel.Variable("user", cel.StringType),
+	cel.Variable("kubeUser", cel.StringType),
+	cel.Variable("time", cel.TimestampType),
+}
+
+var (
+	expressionEnv     *cel.Env
+	expressionEnvErr  error
+	expressionEnvOnce sync.Once
+
+	// Compiled expressions, so that each expression is compiled only once
+	programs   = make(map[string]cel.Program)
+	programsMu sync.Mutex
+)
+
+// compileExpression returns the program of the provided expression, compiling it if needed.
+func compileExpression(expression string) (cel.Program, error) {
+	programsMu.Lock()
+	defer programsMu.Unlock()
+	if program, ok := programs[expression]; ok {
+		return program, nil
+	}
+
+	expressionEnvOnce.Do(func() {
+		expressionEnv, expressionEnvErr = cel.NewEnv(expressionVariables...)
+	})
+	if expressionEnvErr != nil {
+		return nil, expressionEnvErr
+	}
+	ast, issues := expressionEnv.Compile(expression)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("invalid expression %q: must evaluate to a bool, not %s", expression, ast.OutputType())
+	}
+	program, err := expressionEnv.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, err)
+	}
+	programs[expression] = program
+	return program, nil
+}
+
+// expressionInput returns the values of the variables available to the expressions.
+func expressionInput(args *utils.ParsedArgs, commandLen int, env *Environment) map[string]any {
+	if env == nil {
+		env = &Environment{}
+	}
+	resources := make([]map[string]string, 0)
+	for _, ref := range args.Resources(commandLen) {
+		resources = append(resources, map[string]string{"kind": ref.Kind, "name": ref.Name})
+	}
+	flags := make(map[string]string, len(args.Flags))
+	for name := range args.Flags {
+		flags[name], _ = args.GetFlag(name)
+	}
+	return map[string]any{
+		"tool":          args.Tool,
+		"verb":          args.Verb(),
+		"args":          args.Positionals,
+		"resources":     resources,
+		"flags":         flags,
+		"context":       env.Context,
+		"ns":            env.Namespace,
+		"allNamespaces": env.AllNamespaces,
+		"cluster":       env.Cluster,
+		"user":          env.User,
+		"kubeUser":      env.KubeUser,
+		"time":          env.Time,
+	}
+}
+
+// matchesExpression returns true if the rule has no expression, or if its
+// expression evaluates to true. Expressions failing to evaluate match the
+// command, so that errors don't make protected commands run freely.
+func (r Rule) matchesExpression(args *utils.ParsedArgs, env *Environment) bool {
+	if r.When == "" {
+		return true
+	}
+	program, err := compileExpression(r.When)
+	if err != nil {
+		return true
+	}
+	out, _, err := program.Eval(expressionInput(args, len(strings.Fields(r.Command)), env))
+	if err != nil {
+		return true
+	}
+	res, ok := out.Value().(bool)
+	return !ok || res
+}
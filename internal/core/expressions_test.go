@@ -0,0 +1,110 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/telemaco019/kubesafe/internal/utils"
+	"gotest.tools/assert"
+)
+
+func TestRule_MatchesExpression(t *testing.T) {
+	env := &Environment{
+		Context:   "prod-eu",
+		Namespace: "kube-system",
+		Cluster:   "https://prod-eu.example.com",
+		User:      "alice",
+		Time:      time.Date(2025, 1, 4, 22, 0, 0, 0, time.UTC),
+	}
+	testCases := []struct {
+		name string
+		when string
+		args []string
+		want bool
+	}{
+		{
+			name: "No expression",
+			args: []string{"delete", "pod", "x"},
+			want: true,
+		},
+		{
+			name: "Namespace and context",
+			when: `context.matches("^prod-") && ns.matches("^kube-") && !("dry-run" in flags)`,
+			args: []string{"delete", "pod", "x"},
+			want: true,
+		},
+		{
+			name: "Flag set",
+			when: `context.matches("^prod-") && ns.matches("^kube-") && !("dry-run" in flags)`,
+			args: []string{"delete", "pod", "x", "--dry-run=server"},
+			want: false,
+		},
+		{
+			name: "Resources",
+			when: `resources.exists(r, r.kind == "pod" && r.name.startsWith("etcd-"))`,
+			args: []string{"delete", "pod", "etcd-0"},
+			want: true,
+		},
+		{
+			name: "Flag value",
+			when: `int(flags["replicas"]) < 1`,
+			args: []string{"scale", "deploy/x", "--replicas=3"},
+			want: false,
+		},
+		{
+			name: "Time, cluster and user",
+			when: `time.getDayOfWeek() == 6 && cluster.contains("prod") && user != "bob"`,
+			args: []string{"delete", "pod", "x"},
+			want: true,
+		},
+		{
+			name: "Evaluation errors match",
+			when: `flags["missing"] == "x"`,
+			args: []string{"delete", "pod", "x"},
+			want: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := Rule{Command: "delete", When: tc.when}
+			assert.NilError(t, rule.validate())
+			assert.Equal(t, rule.matchesExpression(utils.ParseArgs("kubectl", tc.args), env), tc.want)
+		})
+	}
+}
+
+func TestContextConf_MatchExpression(t *testing.T) {
+	contextConf := NewContextConf("prod", make([]string, 0))
+	contextConf.Rules = []Rule{{Command: "delete", When: `ns.matches("^kube-")`, Severity: SEVERITY_DENY}}
+	args := utils.ParseArgs("kubectl", []string{"delete", "pod", "x"})
+
+	rule, ok := contextConf.Match(args, &Environment{Namespace: "kube-system"})
+	assert.Equal(t, ok, true)
+	assert.Equal(t, rule.Severity, SEVERITY_DENY)
+
+	_, ok = contextConf.Match(args, &Environment{Namespace: "default"})
+	assert.Equal(t, ok, false)
+}
+
+func TestRule_ValidateExpression(t *testing.T) {
+	assert.ErrorContains(t, Rule{Command: "delete", When: `ns ==`}.validate(), "invalid expression")
+	assert.ErrorContains(t, Rule{Command: "delete", When: `ns`}.validate(), "must evaluate to a bool")
+	assert.ErrorContains(t, Rule{Command: "delete", When: `foo == "bar"`}.validate(), "invalid expression")
+}
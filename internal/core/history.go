@@ -0,0 +1,96 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/telemaco019/kubesafe/internal/utils"
+)
+
+// AuditFilter selects the records of the audit log shown in the history.
+// Empty fields match any record.
+type AuditFilter struct {
+	// Context is the name of the context, or a regex matching it.
+	Context string
+	// Namespace is the name of the namespace, or a regex matching it.
+	Namespace string
+	// Verb is the subcommand invoked on the wrapped tool, e.g. "delete".
+	Verb     string
+	Decision string
+	// Since and Until limit the records to the provided time range.
+	Since time.Time
+	Until time.Time
+}
+
+func matchesNameOrRegex(pattern string, value string) bool {
+	if pattern == "" || pattern == value {
+		return true
+	}
+	return utils.IsRegex(pattern) && utils.RegexMatches(pattern, value)
+}
+
+func (f AuditFilter) Validate() error {
+	if f.Decision != "" && !slices.Contains(DECISIONS, f.Decision) {
+		return fmt.Errorf("unknown decision %q, must be one of %s", f.Decision, strings.Join(DECISIONS, ", "))
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && f.Since.After(f.Until) {
+		return fmt.Errorf("the start of the time range must be before its end")
+	}
+	return nil
+}
+
+func (f AuditFilter) Matches(record AuditRecord) bool {
+	if !matchesNameOrRegex(f.Context, record.Context) {
+		return false
+	}
+	if !matchesNameOrRegex(f.Namespace, record.Namespace) {
+		return false
+	}
+	if f.Decision != "" && f.Decision != record.Decision {
+		return false
+	}
+	if !f.Since.IsZero() && record.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && record.Time.After(f.Until) {
+		return false
+	}
+	if f.Verb != "" && f.Verb != record.Verb() {
+		return false
+	}
+	return true
+}
+
+// Verb returns the subcommand invoked on the wrapped tool, e.g. "delete".
+func (r AuditRecord) Verb() string {
+	return utils.ParseArgs(r.Command, r.Args).Verb()
+}
+
+// Filter returns the records matching the filter.
+func (f AuditFilter) Filter(records []AuditRecord) []AuditRecord {
+	res := make([]AuditRecord, 0)
+	for _, r := range records {
+		if f.Matches(r) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestAuditFilter_Matches(t *testing.T) {
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	record := AuditRecord{
+		Time:      now,
+		Command:   "kubectl",
+		Args:      []string{"-n", "backend", "delete", "deployment", "api"},
+		Context:   "prod-eu",
+		Namespace: "backend",
+		Decision:  DECISION_APPROVED,
+	}
+	testCases := []struct {
+		name     string
+		filter   AuditFilter
+		expected bool
+	}{
+		{
+			name:     "Empty filter",
+			filter:   AuditFilter{},
+			expected: true,
+		},
+		{
+			name:     "Context",
+			filter:   AuditFilter{Context: "prod-eu"},
+			expected: true,
+		},
+		{
+			name:     "Context prefix is not a match",
+			filter:   AuditFilter{Context: "prod"},
+			expected: false,
+		},
+		{
+			name:     "Context regex",
+			filter:   AuditFilter{Context: "prod-.*"},
+			expected: true,
+		},
+		{
+			name:     "Namespace",
+			filter:   AuditFilter{Namespace: "frontend"},
+			expected: false,
+		},
+		{
+			name:     "Verb",
+			filter:   AuditFilter{Verb: "delete"},
+			expected: true,
+		},
+		{
+			name:     "Other verb",
+			filter:   AuditFilter{Verb: "apply"},
+			expected: false,
+		},
+		{
+			name:     "Decision",
+			filter:   AuditFilter{Decision: DECISION_CANCELED},
+			expected: false,
+		},
+		{
+			name:     "Time range",
+			filter:   AuditFilter{Since: now.Add(-time.Hour), Until: now},
+			expected: true,
+		},
+		{
+			name:     "Before time range",
+			filter:   AuditFilter{Since: now.Add(time.Minute)},
+			expected: false,
+		},
+		{
+			name:     "After time range",
+			filter:   AuditFilter{Until: now.Add(-time.Minute)},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.filter.Matches(record), tc.expected)
+		})
+	}
+}
+
+func TestAuditFilter_Validate(t *testing.T) {
+	now := time.Now()
+	assert.NilError(t, AuditFilter{Decision: DECISION_DENIED}.Validate())
+	assert.ErrorContains(t, AuditFilter{Decision: "rejected"}.Validate(), "unknown decision")
+	assert.ErrorContains(t, AuditFilter{Since: now, Until: now.Add(-time.Hour)}.Validate(), "time range")
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// HOOK_ALLOW runs the command without asking for confirmation.
+	HOOK_ALLOW = "allow"
+	// HOOK_DENY cancels the command.
+	HOOK_DENY = "deny"
+	// HOOK_ASK asks the user for confirmation before running the command.
+	HOOK_ASK = "ask"
+)
+
+var HOOK_DECISIONS = []string{
+	HOOK_ALLOW,
+	HOOK_DENY,
+	HOOK_ASK,
+}
+
+// DEFAULT_HOOK_TIMEOUT is the time a hook can run for, if it doesn't define its own timeout.
+const DEFAULT_HOOK_TIMEOUT = 10 * time.Second
+
+// Hook is an external executable invoked by kubesafe. It receives a JSON document on stdin.
+type Hook struct {
+	// Command is the path of the executable.
+	Command string `yaml:"command"`
+	// Args are the arguments passed to the executable.
+	Args []string `yaml:"args,omitempty"`
+	// Timeout is the time the hook can run for, e.g. "5s". If zero, DEFAULT_HOOK_TIMEOUT is used.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+func (h Hook) String() string {
+	return strings.Join(append([]string{h.Command}, h.Args...), " ")
+}
+
+func (h Hook) validate() error {
+	if h.Command == "" {
+		return fmt.Errorf("hook command cannot be empty")
+	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("hook %q: timeout cannot be negative", h.Command)
+	}
+	return nil
+}
+
+func (h Hook) timeout() time.Duration {
+	if h.Timeout == 0 {
+		return DEFAULT_HOOK_TIMEOUT
+	}
+	return h.Timeout
+}
+
+// Run invokes the hook with the provided request encoded as JSON on stdin, and returns its stdout.
+func (h Hook) Run(request any) ([]byte, error) {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout())
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, h.Command, h.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	// Don't wait for processes started by the hook that keep its output open
+	cmd.WaitDelay = time.Second
+	err = cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("hook %q timed out after %s", h.Command, h.timeout())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("hook %q failed: %w", h.Command, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// PreHookRequest is the document sent to pre-execution hooks.
+type PreHookRequest struct {
+	// Tool is the name of the wrapped tool, e.g. "kubectl" or "helm".
+	Tool string `json:"tool"`
+	// Command is the wrapped command as invoked by the user.
+	Command string `json:"command"`
+	// Args are the arguments of the wrapped command.
+	Args          []string `json:"args"`
+	Context       string   `json:"context"`
+	Namespace     string   `json:"namespace"`
+	AllNamespaces bool     `json:"allNamespaces"`
+	// Rule is the protection rule matched by the command.
+	Rule string `json:"rule"`
+	// Severity is the severity of the matched rule.
+	Severity string `json:"severity"`
+}
+
+// PreHookResponse is the document returned by pre-execution hooks on stdout.
+type PreHookResponse struct {
+	// Decision is one of "allow", "deny" and "ask".
+	Decision string `json:"decision"`
+	// Message is shown to the user.
+	Message string `json:"message,omitempty"`
+}
+
+// Decide invokes the hook as a pre-execution hook, and returns its decision on the provided request.
+func (h Hook) Decide(request PreHookRequest) (PreHookResponse, error) {
+	var res PreHookResponse
+	output, err := h.Run(request)
+	if err != nil {
+		return res, err
+	}
+	if err = json.Unmarshal(output, &res); err != nil {
+		return res, fmt.Errorf("hook %q returned an invalid response: %w", h.Command, err)
+	}
+	if !slices.Contains(HOOK_DECISIONS, res.Decision) {
+		return res, fmt.Errorf(
+			"hook %q returned an unknown decision %q, must be one of %s",
+			h.Command,
+			res.Decision,
+			strings.Join(HOOK_DECISIONS, ", "),
+		)
+	}
+	return res, nil
+}
+
+const (
+	// APPROVAL_USER means that the user confirmed the command.
+	APPROVAL_USER = "user"
+	// APPROVAL_HOOK means that the pre-execution hooks allowed the command.
+	APPROVAL_HOOK = "hook"
+	// APPROVAL_NONE means that the command ran without approval, e.g. with the "warn" severity.
+	APPROVAL_NONE = "none"
+)
+
+// PostHookRequest is the document sent to post-execution hooks, once the command has run.
+type PostHookRequest struct {
+	// Tool is the name of the wrapped tool, e.g. "kubectl" or "helm".
+	Tool string `json:"tool"`
+	// Command is the wrapped command as invoked by the user.
+	Command string `json:"command"`
+	// Args are the arguments of the wrapped command.
+	Args          []string `json:"args"`
+	Context       string   `json:"context"`
+	Namespace     string   `json:"namespace"`
+	AllNamespaces bool     `json:"allNamespaces"`
+	// Rule is the protection rule matched by the command.
+	Rule string `json:"rule"`
+	// User is the user running kubesafe.
+	User string `json:"user"`
+	// Approval is how the command was approved, one of "user", "hook" and "none".
+	Approval  string    `json:"approval"`
+	StartedAt time.Time `json:"startedAt"`
+	// DurationMs is the time the command ran for, in milliseconds.
+	DurationMs int64 `json:"durationMs"`
+	ExitCode   int   `json:"exitCode"`
+}
+
+func validateHooks(hooks []Hook) error {
+	for _, hook := range hooks {
+		if err := hook.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
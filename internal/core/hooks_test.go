@@ -0,0 +1,99 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func shellHook(script string) Hook {
+	return Hook{Command: "sh", Args: []string{"-c", script}}
+}
+
+func TestHook_Decide(t *testing.T) {
+	request := PreHookRequest{Tool: "kubectl", Context: "prod", Namespace: "default", Rule: "delete"}
+	testCases := []struct {
+		name    string
+		hook    Hook
+		want    PreHookResponse
+		wantErr string
+	}{
+		{
+			name: "Allow",
+			hook: shellHook(`cat > /dev/null; echo '{"decision": "allow"}'`),
+			want: PreHookResponse{Decision: HOOK_ALLOW},
+		},
+		{
+			name: "Request is passed on stdin",
+			hook: shellHook(`grep -q '"context":"prod"' && echo '{"decision": "deny", "message": "no on-call"}'`),
+			want: PreHookResponse{Decision: HOOK_DENY, Message: "no on-call"},
+		},
+		{
+			name:    "Unknown decision",
+			hook:    shellHook(`echo '{"decision": "maybe"}'`),
+			wantErr: "unknown decision",
+		},
+		{
+			name:    "Invalid response",
+			hook:    shellHook(`echo allow`),
+			wantErr: "invalid response",
+		},
+		{
+			name:    "Failing hook",
+			hook:    shellHook(`exit 2`),
+			wantErr: "failed",
+		},
+		{
+			name:    "Timeout",
+			hook:    Hook{Command: "sleep", Args: []string{"5"}, Timeout: 100 * time.Millisecond},
+			wantErr: "timed out",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.hook.Decide(request)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, res, tc.want)
+		})
+	}
+}
+
+func TestHook_Validate(t *testing.T) {
+	assert.NilError(t, Hook{Command: "check-oncall", Timeout: time.Second}.validate())
+	assert.ErrorContains(t, Hook{}.validate(), "empty")
+	assert.ErrorContains(t, Hook{Command: "check-oncall", Timeout: -time.Second}.validate(), "negative")
+}
+
+func TestContextConf_GetPostHooks(t *testing.T) {
+	contextConf := NewContextConf("test", []string{"delete"})
+	contextConf.PostHooks = []Hook{{Command: "notify"}}
+	rule := NewRule("delete", "namespace")
+	rule.PostHooks = []Hook{{Command: "smoke-test"}}
+
+	assert.DeepEqual(t, contextConf.GetPostHooks(nil), []Hook{{Command: "notify"}})
+	assert.DeepEqual(t, contextConf.GetPostHooks(&rule), []Hook{{Command: "notify"}, {Command: "smoke-test"}})
+	// The hooks of the context are not modified
+	assert.Equal(t, len(contextConf.PostHooks), 1)
+}
@@ -18,12 +18,25 @@ package core
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/telemaco019/kubesafe/internal/utils"
 )
 
+// KUBECTL_CLUSTER_SCOPED_COMMANDS are the kubectl commands that only act on cluster-scoped resources,
+// so they are protected regardless of the protected namespaces.
+var KUBECTL_CLUSTER_SCOPED_COMMANDS = []string{
+	"certificate",
+	"cordon",
+	"drain",
+	"taint",
+	"uncordon",
+}
+
 var DEFAULT_KUBECTL_PROTECTED_COMMANDS = []string{
-	// Kubectl commands
 	"delete",
 	"patch",
 	"exec",
@@ -32,33 +45,384 @@ var DEFAULT_KUBECTL_PROTECTED_COMMANDS = []string{
 	"run",
 	"port-forward",
 	"edit",
-	// Helm commands
+}
+
+var DEFAULT_HELM_PROTECTED_COMMANDS = []string{
 	"install",
 	"upgrade",
 	"rollback",
 	"uninstall",
 }
 
-type ContextStats struct {
+var DEFAULT_KUBECTL_ALLOWED_COMMANDS = []string{
+	"get",
+	"describe",
+	"logs",
+	"top",
+	"explain",
+	"api-resources",
+	"api-versions",
+	"auth can-i",
+	"cluster-info",
+	"version",
+}
+
+var DEFAULT_HELM_ALLOWED_COMMANDS = []string{
+	"list",
+	"status",
+	"history",
+	"get",
+	"show",
+	"template",
+	"version",
+}
+
+const (
+	// MODE_DENYLIST protects the commands matching the protected commands and rules.
+	MODE_DENYLIST = "denylist"
+	// MODE_ALLOWLIST protects any command not matching the allowed commands.
+	MODE_ALLOWLIST = "allowlist"
+)
+
+// CommandStats counts the decisions taken on the protected commands.
+type CommandStats struct {
+	// PromptedCount is the number of times the user was asked to confirm a command.
+	PromptedCount uint `yaml:"promptedCount"`
+	// ConfirmedCount is the number of commands approved by the user or by the pre-execution hooks.
+	ConfirmedCount uint `yaml:"confirmedCount"`
 	// CanceledCount is the number of times the execution of a command was canceled by the user.
 	CanceledCount uint `yaml:"canceledCount"`
+	// DeniedCount is the number of commands denied by their severity or by the pre-execution hooks.
+	DeniedCount uint `yaml:"deniedCount"`
+	// AllowedCount is the number of commands that ran without confirmation, e.g. dry-runs.
+	AllowedCount uint `yaml:"allowedCount"`
+	// FirstSeen and LastSeen are the times of the first and of the last protected command.
+	FirstSeen time.Time `yaml:"firstSeen,omitempty"`
+	LastSeen  time.Time `yaml:"lastSeen,omitempty"`
 }
-type ContextConf struct {
-	Name              string        `yaml:"name"`
-	IsRegex           bool          `yaml:"isRegex"`
-	ProtectedCommands []string      `yaml:"commands"`
-	Stats             *ContextStats `yaml:"stats"`
+
+func (s *CommandStats) record(decision string, prompted bool, t time.Time) {
+	if prompted {
+		s.PromptedCount += 1
+	}
+	switch decision {
+	case DECISION_APPROVED:
+		s.ConfirmedCount += 1
+	case DECISION_CANCELED:
+		s.CanceledCount += 1
+	case DECISION_DENIED:
+		s.DeniedCount += 1
+	case DECISION_ALLOWED:
+		s.AllowedCount += 1
+	}
+	if s.FirstSeen.IsZero() || t.Before(s.FirstSeen) {
+		s.FirstSeen = t
+	}
+	if t.After(s.LastSeen) {
+		s.LastSeen = t
+	}
+}
+
+// Stats are the stats of the safe contexts. They are stored apart from the settings,
+// so that recording them doesn't rewrite the settings file.
+type Stats struct {
+	Contexts map[string]*ContextStats `yaml:"contexts"`
+}
+
+// NewStats returns the stats stored in the settings of the provided contexts by older versions of kubesafe.
+func NewStats(contexts ...ContextConf) Stats {
+	res := Stats{Contexts: make(map[string]*ContextStats)}
+	for _, c := range contexts {
+		if c.Stats != nil {
+			res.Contexts[c.Name] = c.Stats
+		}
+	}
+	return res
+}
+
+// Get returns the stats of the provided safe context, creating them if needed.
+func (s *Stats) Get(context string) *ContextStats {
+	if s.Contexts == nil {
+		s.Contexts = make(map[string]*ContextStats)
+	}
+	if s.Contexts[context] == nil {
+		s.Contexts[context] = &ContextStats{}
+	}
+	return s.Contexts[context]
 }
 
-func (c *ContextConf) IsProtected(command string) bool {
-	for _, protectedCommand := range c.ProtectedCommands {
-		if command == protectedCommand {
+type ContextStats struct {
+	CommandStats `yaml:",inline"`
+	// Tools breaks down the stats by tool and verb, e.g. Tools["kubectl"]["delete"].
+	Tools map[string]map[string]*CommandStats `yaml:"tools,omitempty"`
+}
+
+// Record updates the stats with the decision taken on a protected command.
+// Prompted is true if the user was asked to confirm the command.
+func (s *ContextStats) Record(tool, verb, decision string, prompted bool, t time.Time) {
+	s.record(decision, prompted, t)
+	if s.Tools == nil {
+		s.Tools = make(map[string]map[string]*CommandStats)
+	}
+	if s.Tools[tool] == nil {
+		s.Tools[tool] = make(map[string]*CommandStats)
+	}
+	if s.Tools[tool][verb] == nil {
+		s.Tools[tool][verb] = &CommandStats{}
+	}
+	s.Tools[tool][verb].record(decision, prompted, t)
+}
+
+// ToolConf contains the protected commands and rules applied to a specific tool.
+type ToolConf struct {
+	ProtectedCommands []string `yaml:"commands,omitempty"`
+	Rules             []Rule   `yaml:"rules,omitempty"`
+	// AllowedCommands are the only commands that are not protected in allowlist mode.
+	AllowedCommands []string `yaml:"allowedCommands,omitempty"`
+}
+
+// isAllowed returns true if the provided command path matches any of the allowed commands.
+func (t ToolConf) isAllowed(commandPath []string) bool {
+	for _, command := range t.AllowedCommands {
+		words := strings.Fields(command)
+		if len(words) > 0 && hasPrefix(commandPath, words) {
 			return true
 		}
 	}
 	return false
 }
 
+type ContextConf struct {
+	Name    string `yaml:"name"`
+	IsRegex bool   `yaml:"isRegex"`
+	// Mode is either "denylist" (default) or "allowlist".
+	Mode string `yaml:"mode,omitempty"`
+	// ProtectedCommands, Rules and AllowedCommands are applied to the tools
+	// that do not have an entry in Tools.
+	ProtectedCommands []string `yaml:"commands"`
+	Rules             []Rule   `yaml:"rules,omitempty"`
+	AllowedCommands   []string `yaml:"allowedCommands,omitempty"`
+	// Tools maps the name of a tool (e.g. kubectl, helm) to its own protected commands and rules.
+	Tools map[string]ToolConf `yaml:"tools,omitempty"`
+	// Namespaces restricts the protection to specific namespaces of the context.
+	Namespaces NamespaceFilter `yaml:"namespaces,omitempty"`
+	// Severity is what happens when a protected command matches a rule without
+	// its own severity. If empty, "confirm" is used.
+	Severity string `yaml:"severity,omitempty"`
+	// Challenge is what the user has to type to confirm a protected command
+	// matching a rule without its own challenge. If empty, "y" is enough.
+	Challenge string `yaml:"challenge,omitempty"`
+	// Retries is the number of wrong answers allowed to the challenge.
+	// If zero, DEFAULT_CHALLENGE_RETRIES is used.
+	Retries int `yaml:"retries,omitempty"`
+	// PreHooks are invoked before running a protected command on the context, after the global ones.
+	PreHooks []Hook `yaml:"preHooks,omitempty"`
+	// PostHooks are invoked after running a protected command on the context.
+	PostHooks []Hook `yaml:"postHooks,omitempty"`
+	// ProtectDryRun disables the automatic pass-through of dry-run and preview
+	// commands (e.g. "kubectl apply --dry-run=server", "helm template").
+	ProtectDryRun bool `yaml:"protectDryRun,omitempty"`
+	// Stats are the stats stored in the settings by older versions of kubesafe.
+	// They are only read to initialize the stats file (see Stats).
+	Stats *ContextStats `yaml:"stats,omitempty"`
+}
+
+// IsProtectedNamespace returns true if the command targets a protected namespace.
+// The namespaces targeted by name (e.g. "kubectl delete ns payments") and the namespaces
+// of the objects of the manifests are checked, falling back to the namespace of the
+// context. Commands on cluster-scoped resources (e.g. nodes) are always protected.
+func (c *ContextConf) IsProtectedNamespace(namespacedContext *utils.NamespacedContext, args *utils.ParsedArgs) bool {
+	// Commands on all namespaces always touch at least one protected namespace
+	if namespacedContext.AllNamespaces {
+		return true
+	}
+	// If the manifests could not be inspected, assume they touch a protected namespace
+	if args.ManifestsIncomplete {
+		return true
+	}
+	// Namespaces touched by the command
+	namespaces := make([]string, 0)
+	// Returns true if the command touches every namespace, or cluster-scoped resources
+	addRef := func(ref utils.ResourceRef, namespace string) bool {
+		switch {
+		case ref.Kind == "namespace" && ref.Name == "":
+			return true
+		case ref.Kind == "namespace":
+			namespaces = append(namespaces, ref.Name)
+		case utils.IsClusterScoped(ref.Kind):
+			return true
+		case namespace != "":
+			namespaces = append(namespaces, namespace)
+		default:
+			namespaces = append(namespaces, namespacedContext.Namespace)
+		}
+		return false
+	}
+	// Resources targeted by name, e.g. "kubectl delete ns payments"
+	if args.Tool == "kubectl" {
+		if slices.Contains(KUBECTL_CLUSTER_SCOPED_COMMANDS, args.Verb()) {
+			return true
+		}
+		if len(args.Positionals) > 1 {
+			for _, ref := range utils.ParseResourceRefs(args.Positionals[1:]) {
+				if addRef(ref, "") {
+					return true
+				}
+			}
+		}
+	}
+	for _, object := range args.Manifests {
+		if addRef(object.Ref(), object.Namespace) {
+			return true
+		}
+	}
+	if len(namespaces) == 0 {
+		namespaces = append(namespaces, namespacedContext.Namespace)
+	}
+	return slices.ContainsFunc(namespaces, c.Namespaces.Matches)
+}
+
+// GetToolConf returns the protected commands and rules applied to the provided command.
+// The entry named after the command (e.g. "oc") is preferred to the one of the tool it
+// is an alias of (e.g. "kubectl"), falling back to the ones of the context if neither exists.
+func (c *ContextConf) GetToolConf(cmd string) ToolConf {
+	tool := utils.ToolName(cmd)
+	// Names are sorted so that the same entry is returned if several resolve to the same tool
+	names := slices.Sorted(maps.Keys(c.Tools))
+	matchers := []func(name string) bool{
+		func(name string) bool { return utils.CommandName(name) == utils.CommandName(cmd) },
+		func(name string) bool { return utils.CommandName(name) == tool },
+		func(name string) bool { return utils.ToolName(name) == tool },
+	}
+	for _, matches := range matchers {
+		for _, name := range names {
+			if matches(name) {
+				return c.Tools[name]
+			}
+		}
+	}
+	return ToolConf{
+		ProtectedCommands: c.ProtectedCommands,
+		Rules:             c.Rules,
+		AllowedCommands:   c.AllowedCommands,
+	}
+}
+
+// GetSeverity returns the severity applied when the provided rule matches a command.
+func (c *ContextConf) GetSeverity(rule *Rule) string {
+	if rule != nil && rule.Severity != "" {
+		return rule.Severity
+	}
+	if c.Severity != "" {
+		return c.Severity
+	}
+	return SEVERITY_CONFIRM
+}
+
+// GetPostHooks returns the hooks to invoke after running a command matching the provided rule.
+func (c *ContextConf) GetPostHooks(rule *Rule) []Hook {
+	res := slices.Clone(c.PostHooks)
+	if rule != nil {
+		res = append(res, rule.PostHooks...)
+	}
+	return res
+}
+
+// GetChallenge returns the challenge the user has to solve to confirm a command
+// matching the provided rule, along with the number of wrong answers allowed.
+// The challenge is empty if a plain y/n confirmation is enough.
+func (c *ContextConf) GetChallenge(rule *Rule) (string, int) {
+	challenge, retries := c.Challenge, c.Retries
+	if rule != nil && rule.Challenge != "" {
+		challenge = rule.Challenge
+	}
+	if rule != nil && rule.Retries != 0 {
+		retries = rule.Retries
+	}
+	if challenge == "" && c.GetSeverity(rule) == SEVERITY_TYPED_CONFIRM {
+		challenge = CHALLENGE_CONTEXT
+	}
+	if retries == 0 {
+		retries = DEFAULT_CHALLENGE_RETRIES
+	}
+	return challenge, retries
+}
+
+func (c *ContextConf) IsAllowlist() bool {
+	return c.Mode == MODE_ALLOWLIST
+}
+
+// Match returns the most specific rule protecting the provided command, running in the provided environment.
+// Protected commands are treated as rules applying to any resource.
+// In allowlist mode, commands that are not allowed and do not match
+// any rule are protected by a rule matching their subcommand.
+func (c *ContextConf) Match(args *utils.ParsedArgs, env *Environment) (*Rule, bool) {
+	toolConf := c.GetToolConf(args.Command)
+	rules := make([]Rule, 0, len(toolConf.ProtectedCommands)+len(toolConf.Rules))
+	for _, command := range toolConf.ProtectedCommands {
+		rules = append(rules, NewRule(command))
+	}
+	rules = append(rules, toolConf.Rules...)
+
+	var match *Rule
+	for i, rule := range rules {
+		if !rule.Matches(args) || !rule.matchesExpression(args, env) {
+			continue
+		}
+		if match == nil || rule.isMoreSpecificThan(*match) {
+			match = &rules[i]
+		}
+	}
+	if match == nil && c.IsAllowlist() && args.Verb() != "" && !toolConf.isAllowed(args.Positionals) {
+		rule := NewRule(args.Verb())
+		match = &rule
+	}
+	return match, match != nil
+}
+
+func hasPrefix(commandPath []string, words []string) bool {
+	if len(words) > len(commandPath) {
+		return false
+	}
+	for i, word := range words {
+		if commandPath[i] != word {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *ContextConf) Validate() error {
+	if c.Mode != "" && c.Mode != MODE_DENYLIST && c.Mode != MODE_ALLOWLIST {
+		return fmt.Errorf("context %q: unknown mode %q", c.Name, c.Mode)
+	}
+	if err := validateSeverity(c.Severity); err != nil {
+		return fmt.Errorf("context %q: %w", c.Name, err)
+	}
+	if err := validateChallenge(c.Challenge, c.Retries); err != nil {
+		return fmt.Errorf("context %q: %w", c.Name, err)
+	}
+	for _, rule := range c.Rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("context %q: %w", c.Name, err)
+		}
+	}
+	if err := validateHooks(c.PreHooks); err != nil {
+		return fmt.Errorf("context %q: %w", c.Name, err)
+	}
+	if err := validateHooks(c.PostHooks); err != nil {
+		return fmt.Errorf("context %q: %w", c.Name, err)
+	}
+	for tool, toolConf := range c.Tools {
+		for _, rule := range toolConf.Rules {
+			if err := rule.validate(); err != nil {
+				return fmt.Errorf("context %q, tool %q: %w", c.Name, tool, err)
+			}
+		}
+	}
+	return nil
+}
+
 func NewContextConf(
 	contextName string,
 	safeActions []string,
@@ -67,12 +431,17 @@ func NewContextConf(
 		Name:              contextName,
 		ProtectedCommands: safeActions,
 		IsRegex:           utils.IsRegex(contextName),
-		Stats:             &ContextStats{CanceledCount: 0},
 	}
 }
 
 type Settings struct {
-	Contexts []ContextConf `yaml:"contexts"`
+	// PreHooks are invoked before running a protected command on any safe context.
+	PreHooks []Hook `yaml:"preHooks,omitempty"`
+	// Audit configures the audit log of the decisions taken on protected commands.
+	Audit AuditConf `yaml:"audit,omitempty"`
+	// Redaction configures how secrets are redacted from the persisted commands.
+	Redaction RedactionConf `yaml:"redaction,omitempty"`
+	Contexts  []ContextConf `yaml:"contexts"`
 
 	contextLookup  map[string]ContextConf
 	contextRegexes []ContextConf
@@ -92,7 +461,8 @@ func (s *Settings) init() {
 	if s.contextLookup == nil {
 		s.contextLookup = make(map[string]ContextConf)
 	}
-	for _, context := range s.Contexts {
+	for i := range s.Contexts {
+		context := s.Contexts[i]
 		s.contextLookup[context.Name] = context
 		if context.IsRegex {
 			s.contextRegexes = append(s.contextRegexes, context)
@@ -100,6 +470,25 @@ func (s *Settings) init() {
 	}
 }
 
+// Validate returns an error if any of the hooks, the audit log, the redaction or the contexts is not configured correctly.
+func (s *Settings) Validate() error {
+	if err := validateHooks(s.PreHooks); err != nil {
+		return err
+	}
+	if err := s.Audit.validate(); err != nil {
+		return err
+	}
+	if err := s.Redaction.validate(); err != nil {
+		return err
+	}
+	for _, context := range s.Contexts {
+		if err := context.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *Settings) AddContext(context ContextConf) error {
 	if s.ContainsContext(context.Name) {
 		return fmt.Errorf("context %q is already included in safe contexts", context.Name)
@@ -19,7 +19,9 @@ package core
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/telemaco019/kubesafe/internal/utils"
 	"gotest.tools/assert"
 )
 
@@ -164,3 +166,367 @@ func TestGetContextConf(t *testing.T) {
 		})
 	}
 }
+
+func TestContextConf_MatchLongestCommand(t *testing.T) {
+	contextConf := NewContextConf("test", []string{
+		"delete",
+		"rollout restart",
+		"config delete-context",
+		"rollout",
+	})
+	testCases := []struct {
+		name        string
+		commandPath []string
+		wantMatch   string
+		wantOk      bool
+	}{
+		{
+			name:        "Single word",
+			commandPath: []string{"delete", "pod", "x"},
+			wantMatch:   "delete",
+			wantOk:      true,
+		},
+		{
+			name:        "Longest match wins",
+			commandPath: []string{"rollout", "restart", "deploy/x"},
+			wantMatch:   "rollout restart",
+			wantOk:      true,
+		},
+		{
+			name:        "Shorter match",
+			commandPath: []string{"rollout", "status", "deploy/x"},
+			wantMatch:   "rollout",
+			wantOk:      true,
+		},
+		{
+			name:        "Sibling of multi-word command",
+			commandPath: []string{"config", "get-contexts"},
+			wantMatch:   "",
+			wantOk:      false,
+		},
+		{
+			name:        "Partial multi-word command",
+			commandPath: []string{"config"},
+			wantMatch:   "",
+			wantOk:      false,
+		},
+		{
+			name:        "Empty command",
+			commandPath: []string{},
+			wantMatch:   "",
+			wantOk:      false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, ok := contextConf.Match(utils.ParseArgs("kubectl", tc.commandPath), nil)
+			assert.Equal(t, ok, tc.wantOk)
+			if ok {
+				assert.Equal(t, rule.Command, tc.wantMatch)
+			}
+		})
+	}
+}
+
+func TestContextConf_MatchTool(t *testing.T) {
+	contextConf := NewContextConf("test", []string{"delete", "install"})
+	contextConf.Tools = map[string]ToolConf{
+		"kubectl": {ProtectedCommands: []string{"delete"}},
+		"helm":    {ProtectedCommands: []string{"install", "uninstall"}},
+		"oc":      {ProtectedCommands: []string{"adm"}},
+	}
+	testCases := []struct {
+		name string
+		cmd  string
+		args []string
+		want bool
+	}{
+		{name: "kubectl protected", cmd: "kubectl", args: []string{"delete", "pod", "x"}, want: true},
+		{name: "kubectl ignores helm commands", cmd: "kubectl", args: []string{"install", "x"}, want: false},
+		{name: "helm protected", cmd: "/usr/bin/helm", args: []string{"uninstall", "x"}, want: true},
+		{name: "helm ignores kubectl commands", cmd: "helm", args: []string{"delete", "x"}, want: false},
+		{name: "Alias uses kubectl commands", cmd: "kubecolor", args: []string{"delete", "pod", "x"}, want: true},
+		{name: "Alias uses its own commands", cmd: "oc", args: []string{"adm", "drain", "x"}, want: true},
+		{name: "Alias ignores kubectl commands", cmd: "oc", args: []string{"delete", "pod", "x"}, want: false},
+		{name: "Unknown tool uses fallback", cmd: "argocd", args: []string{"install", "x"}, want: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, ok := contextConf.Match(utils.ParseArgs(tc.cmd, tc.args), nil)
+			assert.Equal(t, ok, tc.want)
+		})
+	}
+}
+
+func TestContextConf_GetToolConf(t *testing.T) {
+	contextConf := NewContextConf("test", []string{"apply"})
+	contextConf.Tools = map[string]ToolConf{
+		"kubectl": {ProtectedCommands: []string{"delete"}},
+		"oc":      {ProtectedCommands: []string{"adm"}},
+		"Helm":    {ProtectedCommands: []string{"uninstall"}},
+	}
+	testCases := []struct {
+		cmd  string
+		want []string
+	}{
+		{cmd: "kubectl", want: []string{"delete"}},
+		{cmd: "/usr/bin/oc", want: []string{"adm"}},
+		{cmd: "oc.exe", want: []string{"adm"}},
+		{cmd: "kubecolor", want: []string{"delete"}},
+		{cmd: "helm", want: []string{"uninstall"}},
+		{cmd: "argocd", want: []string{"apply"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.cmd, func(t *testing.T) {
+			// The entry must not depend on the iteration order of the tools
+			for i := 0; i < 20; i++ {
+				assert.DeepEqual(t, contextConf.GetToolConf(tc.cmd).ProtectedCommands, tc.want)
+			}
+		})
+	}
+
+	t.Run("Alias without a kubectl entry", func(t *testing.T) {
+		contextConf.Tools = map[string]ToolConf{
+			"oc":        {ProtectedCommands: []string{"adm"}},
+			"kubecolor": {ProtectedCommands: []string{"delete"}},
+		}
+		for i := 0; i < 20; i++ {
+			assert.DeepEqual(t, contextConf.GetToolConf("kubectl").ProtectedCommands, []string{"delete"})
+		}
+	})
+}
+
+func TestContextConf_IsProtectedNamespace(t *testing.T) {
+	testCases := []struct {
+		name       string
+		filter     NamespaceFilter
+		namespace  string
+		all        bool
+		manifests  []utils.ManifestObject
+		args       []string
+		incomplete bool
+		want       bool
+	}{
+		{name: "Empty filter", filter: NamespaceFilter{}, namespace: "foo", want: true},
+		{name: "Included", filter: NamespaceFilter{Include: []string{"payments", "kube-system"}}, namespace: "kube-system", want: true},
+		{name: "Not included", filter: NamespaceFilter{Include: []string{"payments", "kube-system"}}, namespace: "default", want: false},
+		{name: "Included by regex", filter: NamespaceFilter{Include: []string{"^kube-.*"}}, namespace: "kube-public", want: true},
+		{name: "Excluded", filter: NamespaceFilter{Exclude: []string{"sandbox-.*"}}, namespace: "sandbox-1", want: false},
+		{name: "Not excluded", filter: NamespaceFilter{Exclude: []string{"sandbox-.*"}}, namespace: "payments", want: true},
+		{name: "Exclude wins", filter: NamespaceFilter{Include: []string{".*"}, Exclude: []string{"dev"}}, namespace: "dev", want: false},
+		{name: "All namespaces", filter: NamespaceFilter{Include: []string{"payments"}}, namespace: "default", all: true, want: true},
+		{
+			name:      "Manifest in protected namespace",
+			filter:    NamespaceFilter{Include: []string{"payments"}},
+			namespace: "default",
+			manifests: []utils.ManifestObject{{Kind: "ConfigMap", Name: "a"}, {Kind: "Deployment", Name: "b", Namespace: "payments"}},
+			want:      true,
+		},
+		{
+			name:      "Manifests in other namespaces",
+			filter:    NamespaceFilter{Include: []string{"payments"}},
+			namespace: "payments",
+			manifests: []utils.ManifestObject{{Kind: "Deployment", Name: "b", Namespace: "other"}},
+			want:      false,
+		},
+		{
+			name:      "Manifest without namespace",
+			filter:    NamespaceFilter{Include: []string{"payments"}},
+			namespace: "payments",
+			manifests: []utils.ManifestObject{{Kind: "Deployment", Name: "b"}},
+			want:      true,
+		},
+		{
+			name:      "Namespace manifest",
+			filter:    NamespaceFilter{Include: []string{"payments"}},
+			namespace: "default",
+			manifests: []utils.ManifestObject{{Kind: "Namespace", Name: "payments"}},
+			want:      true,
+		},
+		{
+			name:       "Manifests that could not be inspected",
+			filter:     NamespaceFilter{Include: []string{"payments"}},
+			namespace:  "default",
+			args:       []string{"apply", "-k", "overlays/prod"},
+			incomplete: true,
+			want:       true,
+		},
+		{
+			name:      "Cluster-scoped manifest",
+			filter:    NamespaceFilter{Include: []string{"payments"}},
+			namespace: "default",
+			manifests: []utils.ManifestObject{{Kind: "ClusterRole", Name: "admin"}},
+			want:      true,
+		},
+		{
+			name:      "Protected namespace targeted by name",
+			filter:    NamespaceFilter{Include: []string{"payments"}},
+			namespace: "default",
+			args:      []string{"delete", "ns", "payments"},
+			want:      true,
+		},
+		{
+			name:      "Other namespace targeted by name",
+			filter:    NamespaceFilter{Include: []string{"payments"}},
+			namespace: "payments",
+			args:      []string{"delete", "namespace/dev"},
+			want:      false,
+		},
+		{
+			name:      "All namespaces targeted",
+			filter:    NamespaceFilter{Include: []string{"payments"}},
+			namespace: "default",
+			args:      []string{"delete", "ns", "--all"},
+			want:      true,
+		},
+		{
+			name:      "Cluster-scoped resource",
+			filter:    NamespaceFilter{Include: []string{"payments"}},
+			namespace: "default",
+			args:      []string{"delete", "node", "n1"},
+			want:      true,
+		},
+		{
+			name:      "Cluster-scoped command",
+			filter:    NamespaceFilter{Include: []string{"payments"}},
+			namespace: "default",
+			args:      []string{"drain", "n1"},
+			want:      true,
+		},
+		{
+			name:      "Namespaced resource in other namespace",
+			filter:    NamespaceFilter{Include: []string{"payments"}},
+			namespace: "default",
+			args:      []string{"delete", "pod", "x"},
+			want:      false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			contextConf := NewContextConf("test", []string{"delete"})
+			contextConf.Namespaces = tc.filter
+			namespacedContext := utils.NewNamespacedContext(tc.namespace, "test")
+			namespacedContext.AllNamespaces = tc.all
+			if tc.args == nil {
+				tc.args = []string{"apply", "-f", "x.yaml"}
+			}
+			args := utils.ParseArgs("kubectl", tc.args)
+			args.Manifests = tc.manifests
+			args.ManifestsIncomplete = tc.incomplete
+			assert.Equal(t, contextConf.IsProtectedNamespace(namespacedContext, args), tc.want)
+		})
+	}
+}
+
+func TestContextConf_MatchAllowlist(t *testing.T) {
+	contextConf := NewContextConf("test", make([]string, 0))
+	contextConf.Mode = MODE_ALLOWLIST
+	contextConf.Tools = map[string]ToolConf{
+		"kubectl": {
+			AllowedCommands: []string{"get", "describe", "auth can-i"},
+			Rules:           []Rule{NewRule("get", "secret")},
+		},
+	}
+	testCases := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{name: "Allowed command", args: []string{"get", "pods"}, want: false},
+		{name: "Allowed multi-word command", args: []string{"auth", "can-i", "delete", "pods"}, want: false},
+		{name: "Sibling of allowed multi-word command", args: []string{"auth", "reconcile", "-f", "x.yaml"}, want: true},
+		{name: "Command not allowed", args: []string{"delete", "pod", "x"}, want: true},
+		{name: "Unknown plugin", args: []string{"foo", "bar"}, want: true},
+		{name: "Rules win over allowed commands", args: []string{"get", "secrets"}, want: true},
+		{name: "No subcommand", args: []string{"--help"}, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, ok := contextConf.Match(utils.ParseArgs("kubectl", tc.args), nil)
+			assert.Equal(t, ok, tc.want)
+		})
+	}
+}
+
+func TestContextConf_GetSeverity(t *testing.T) {
+	contextConf := NewContextConf("test", []string{"delete"})
+	rule := NewRule("delete", "namespace")
+	assert.Equal(t, contextConf.GetSeverity(nil), SEVERITY_CONFIRM)
+	assert.Equal(t, contextConf.GetSeverity(&rule), SEVERITY_CONFIRM)
+
+	contextConf.Severity = SEVERITY_WARN
+	assert.Equal(t, contextConf.GetSeverity(&rule), SEVERITY_WARN)
+
+	rule.Severity = SEVERITY_DENY
+	assert.Equal(t, contextConf.GetSeverity(&rule), SEVERITY_DENY)
+	assert.ErrorContains(t, (&ContextConf{Name: "test", Severity: "block"}).Validate(), "unknown severity")
+}
+
+func TestContextConf_GetChallenge(t *testing.T) {
+	contextConf := NewContextConf("test", []string{"delete"})
+	rule := NewRule("delete", "namespace")
+	challenge, retries := contextConf.GetChallenge(&rule)
+	assert.Equal(t, challenge, "")
+	assert.Equal(t, retries, DEFAULT_CHALLENGE_RETRIES)
+
+	// Typed confirmations default to the context name
+	contextConf.Severity = SEVERITY_TYPED_CONFIRM
+	challenge, _ = contextConf.GetChallenge(&rule)
+	assert.Equal(t, challenge, CHALLENGE_CONTEXT)
+
+	contextConf.Challenge = CHALLENGE_RANDOM
+	contextConf.Retries = 1
+	challenge, retries = contextConf.GetChallenge(nil)
+	assert.Equal(t, challenge, CHALLENGE_RANDOM)
+	assert.Equal(t, retries, 1)
+
+	rule.Challenge = CHALLENGE_RESOURCE
+	rule.Retries = 5
+	challenge, retries = contextConf.GetChallenge(&rule)
+	assert.Equal(t, challenge, CHALLENGE_RESOURCE)
+	assert.Equal(t, retries, 5)
+}
+
+func TestContextStats_Record(t *testing.T) {
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	stats := &ContextStats{}
+	stats.Record("kubectl", "delete", DECISION_APPROVED, true, now)
+	stats.Record("kubectl", "delete", DECISION_CANCELED, true, now.Add(time.Hour))
+	stats.Record("kubectl", "apply", DECISION_ALLOWED, false, now.Add(-time.Hour))
+	stats.Record("helm", "uninstall", DECISION_DENIED, false, now)
+
+	assert.DeepEqual(t, stats.CommandStats, CommandStats{
+		PromptedCount:  2,
+		ConfirmedCount: 1,
+		CanceledCount:  1,
+		DeniedCount:    1,
+		AllowedCount:   1,
+		FirstSeen:      now.Add(-time.Hour),
+		LastSeen:       now.Add(time.Hour),
+	})
+	assert.DeepEqual(t, *stats.Tools["kubectl"]["delete"], CommandStats{
+		PromptedCount:  2,
+		ConfirmedCount: 1,
+		CanceledCount:  1,
+		FirstSeen:      now,
+		LastSeen:       now.Add(time.Hour),
+	})
+	assert.Equal(t, stats.Tools["kubectl"]["apply"].AllowedCount, uint(1))
+	assert.Equal(t, stats.Tools["helm"]["uninstall"].DeniedCount, uint(1))
+}
+
+func TestNewStats(t *testing.T) {
+	legacy := NewContextConf("prod", []string{"delete"})
+	legacy.Stats = &ContextStats{CommandStats: CommandStats{CanceledCount: 3}}
+	stats := NewStats(legacy, NewContextConf("dev", []string{"delete"}))
+
+	assert.Equal(t, stats.Get("prod").CanceledCount, uint(3))
+	assert.Equal(t, stats.Get("dev").CanceledCount, uint(0))
+	stats.Get("staging").Record("kubectl", "delete", DECISION_CANCELED, true, time.Now())
+	assert.Equal(t, stats.Get("staging").CanceledCount, uint(1))
+}
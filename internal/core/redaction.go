@@ -0,0 +1,41 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import "github.com/telemaco019/kubesafe/internal/utils"
+
+// RedactionConf configures how secrets are redacted from the arguments of the
+// wrapped commands before kubesafe persists them (e.g. in the audit log).
+type RedactionConf struct {
+	// Flags are the flags whose values are redacted, in addition to the built-in ones (e.g. "db-password").
+	Flags []string `yaml:"flags,omitempty"`
+	// Patterns are regular expressions matched against the arguments, in addition to the built-in ones.
+	// The parts captured by the groups of the pattern are redacted, or the whole match if there are no groups.
+	Patterns []string `yaml:"patterns,omitempty"`
+	// DisableDefaults turns off the built-in flags and patterns for kubectl and helm.
+	DisableDefaults bool `yaml:"disableDefaults,omitempty"`
+}
+
+// Redactor returns the redactor of the secrets in the arguments of the wrapped commands.
+func (c RedactionConf) Redactor() (*utils.Redactor, error) {
+	return utils.NewRedactor(c.Flags, c.Patterns, !c.DisableDefaults)
+}
+
+func (c RedactionConf) validate() error {
+	_, err := c.Redactor()
+	return err
+}
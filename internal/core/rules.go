@@ -0,0 +1,337 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/telemaco019/kubesafe/internal/utils"
+)
+
+const (
+	// SEVERITY_WARN prints a warning and runs the command.
+	SEVERITY_WARN = "warn"
+	// SEVERITY_CONFIRM asks for a y/n confirmation before running the command.
+	SEVERITY_CONFIRM = "confirm"
+	// SEVERITY_TYPED_CONFIRM asks to type the context name before running the command.
+	SEVERITY_TYPED_CONFIRM = "typed-confirm"
+	// SEVERITY_DENY never runs the command.
+	SEVERITY_DENY = "deny"
+)
+
+var SEVERITIES = []string{
+	SEVERITY_WARN,
+	SEVERITY_CONFIRM,
+	SEVERITY_TYPED_CONFIRM,
+	SEVERITY_DENY,
+}
+
+func validateSeverity(severity string) error {
+	if severity != "" && !slices.Contains(SEVERITIES, severity) {
+		return fmt.Errorf("unknown severity %q, must be one of %s", severity, strings.Join(SEVERITIES, ", "))
+	}
+	return nil
+}
+
+const (
+	// CHALLENGE_CONTEXT asks to type the name of the context.
+	CHALLENGE_CONTEXT = "context"
+	// CHALLENGE_RESOURCE asks to type the name of the targeted resources.
+	CHALLENGE_RESOURCE = "resource"
+	// CHALLENGE_RANDOM asks to type a short randomly generated word.
+	CHALLENGE_RANDOM = "random"
+)
+
+var CHALLENGES = []string{
+	CHALLENGE_CONTEXT,
+	CHALLENGE_RESOURCE,
+	CHALLENGE_RANDOM,
+}
+
+// DEFAULT_CHALLENGE_RETRIES is the number of wrong answers allowed to a challenge before canceling the command.
+const DEFAULT_CHALLENGE_RETRIES = 2
+
+func validateChallenge(challenge string, retries int) error {
+	if challenge != "" && !slices.Contains(CHALLENGES, challenge) {
+		return fmt.Errorf("unknown challenge %q, must be one of %s", challenge, strings.Join(CHALLENGES, ", "))
+	}
+	if retries < 0 {
+		return fmt.Errorf("challenge retries cannot be negative")
+	}
+	return nil
+}
+
+const (
+	FLAG_OP_PRESENT = "present"
+	FLAG_OP_EQUALS  = "equals"
+	FLAG_OP_LT      = "lt"
+	FLAG_OP_LTE     = "lte"
+	FLAG_OP_GT      = "gt"
+	FLAG_OP_GTE     = "gte"
+)
+
+var flagOperatorSymbols = map[string]string{
+	FLAG_OP_EQUALS: "=",
+	FLAG_OP_LT:     "<",
+	FLAG_OP_LTE:    "<=",
+	FLAG_OP_GT:     ">",
+	FLAG_OP_GTE:    ">=",
+}
+
+// FlagCondition is a condition on a flag of the wrapped command.
+type FlagCondition struct {
+	// Name is the name of the flag, e.g. "force", "--grace-period" or "-A".
+	Name string `yaml:"name"`
+	// Operator is one of "present" (default), "equals", "lt", "lte", "gt" and "gte".
+	Operator string `yaml:"op,omitempty"`
+	// Value is the value the flag is compared to. Not used by the "present" operator.
+	Value string `yaml:"value,omitempty"`
+}
+
+func (f FlagCondition) operator() string {
+	if f.Operator == "" {
+		return FLAG_OP_PRESENT
+	}
+	return f.Operator
+}
+
+func (f FlagCondition) String() string {
+	name := "--" + strings.TrimLeft(f.Name, "-")
+	if symbol, ok := flagOperatorSymbols[f.operator()]; ok {
+		return name + symbol + f.Value
+	}
+	return name
+}
+
+func (f FlagCondition) validate() error {
+	op := f.operator()
+	if _, ok := flagOperatorSymbols[op]; !ok && op != FLAG_OP_PRESENT {
+		return fmt.Errorf("flag %q: unknown operator %q", f.Name, f.Operator)
+	}
+	if op != FLAG_OP_PRESENT && op != FLAG_OP_EQUALS {
+		if _, err := strconv.ParseFloat(f.Value, 64); err != nil {
+			return fmt.Errorf("flag %q: operator %q requires a numeric value", f.Name, f.Operator)
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the condition holds for the provided command.
+func (f FlagCondition) Matches(args *utils.ParsedArgs) bool {
+	name := utils.GetToolSpec(args.Tool).LongFlagName(f.Name)
+	value, ok := args.GetFlag(name)
+	if !ok {
+		return false
+	}
+	op := f.operator()
+	switch op {
+	case FLAG_OP_PRESENT:
+		// Boolean flags can be explicitly disabled, e.g. "--force=false"
+		return value != "false"
+	case FLAG_OP_EQUALS:
+		return value == f.Value
+	}
+	actual, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+	expected, err := strconv.ParseFloat(f.Value, 64)
+	if err != nil {
+		return false
+	}
+	switch op {
+	case FLAG_OP_LT:
+		return actual < expected
+	case FLAG_OP_LTE:
+		return actual <= expected
+	case FLAG_OP_GT:
+		return actual > expected
+	case FLAG_OP_GTE:
+		return actual >= expected
+	}
+	return false
+}
+
+// NamespaceFilter restricts the protection to the namespaces matching any of the
+// Include patterns (or any namespace, if empty) and none of the Exclude patterns.
+// Patterns can be either namespace names or regular expressions.
+type NamespaceFilter struct {
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+}
+
+func (f NamespaceFilter) IsEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Matches returns true if the provided namespace is protected by the filter.
+func (f NamespaceFilter) Matches(namespace string) bool {
+	if matchesAnyPattern(f.Exclude, namespace) {
+		return false
+	}
+	return len(f.Include) == 0 || matchesAnyPattern(f.Include, namespace)
+}
+
+func (f NamespaceFilter) String() string {
+	res := make([]string, 0, len(f.Include)+len(f.Exclude))
+	res = append(res, f.Include...)
+	for _, exclude := range f.Exclude {
+		res = append(res, "!"+exclude)
+	}
+	return strings.Join(res, ", ")
+}
+
+func matchesAnyPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if pattern == value || (utils.IsRegex(pattern) && utils.RegexMatches(pattern, value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule protects a command, optionally scoped to the resources it targets
+// and to the flags it is invoked with.
+type Rule struct {
+	// Command is the protected command, e.g. "delete" or "rollout restart".
+	Command string `yaml:"command"`
+	// Resources restricts the rule to the provided resource kinds (e.g. "namespace", "pvc").
+	// If empty, the rule applies regardless of the targeted resources.
+	Resources []string `yaml:"resources,omitempty"`
+	// Flags restricts the rule to invocations satisfying all the provided conditions.
+	Flags []FlagCondition `yaml:"flags,omitempty"`
+	// When restricts the rule to the commands for which the provided CEL expression evaluates to true.
+	When string `yaml:"when,omitempty"`
+	// Severity is what happens when the rule matches. If empty, the severity of the context is used.
+	Severity string `yaml:"severity,omitempty"`
+	// Challenge is what the user has to type to confirm the command, instead of "y".
+	// If empty, the challenge of the context is used.
+	Challenge string `yaml:"challenge,omitempty"`
+	// Retries is the number of wrong answers allowed to the challenge. If zero, the retries of the context are used.
+	Retries int `yaml:"retries,omitempty"`
+	// PostHooks are invoked after running a command matching the rule, after the ones of the context.
+	PostHooks []Hook `yaml:"postHooks,omitempty"`
+}
+
+func NewRule(command string, resources ...string) Rule {
+	return Rule{
+		Command:   command,
+		Resources: resources,
+	}
+}
+
+func (r Rule) String() string {
+	res := r.Command
+	if len(r.Resources) > 0 {
+		res = fmt.Sprintf("%s [%s]", res, strings.Join(r.Resources, ", "))
+	}
+	for _, flag := range r.Flags {
+		res = fmt.Sprintf("%s %s", res, flag)
+	}
+	if r.When != "" {
+		res = fmt.Sprintf("%s when %s", res, r.When)
+	}
+	if r.Severity != "" {
+		res = fmt.Sprintf("%s (%s)", res, r.Severity)
+	}
+	return res
+}
+
+func (r Rule) validate() error {
+	if len(strings.Fields(r.Command)) == 0 {
+		return fmt.Errorf("rule command cannot be empty")
+	}
+	if err := validateSeverity(r.Severity); err != nil {
+		return fmt.Errorf("rule %q: %w", r.Command, err)
+	}
+	if err := validateChallenge(r.Challenge, r.Retries); err != nil {
+		return fmt.Errorf("rule %q: %w", r.Command, err)
+	}
+	if err := validateHooks(r.PostHooks); err != nil {
+		return fmt.Errorf("rule %q: %w", r.Command, err)
+	}
+	if r.When != "" {
+		if _, err := compileExpression(r.When); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Command, err)
+		}
+	}
+	for _, flag := range r.Flags {
+		if err := flag.validate(); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Command, err)
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the rule applies to the provided command.
+func (r Rule) Matches(args *utils.ParsedArgs) bool {
+	words := strings.Fields(r.Command)
+	if len(words) == 0 || !hasPrefix(args.Positionals, words) {
+		return false
+	}
+	for _, flag := range r.Flags {
+		if !flag.Matches(args) {
+			return false
+		}
+	}
+	// If the manifests could not be inspected, assume they contain the protected resources
+	if args.ManifestsIncomplete && len(r.Resources) > 0 {
+		return true
+	}
+	return r.matchesResources(args.Resources(len(words)))
+}
+
+func (r Rule) matchesResources(refs []utils.ResourceRef) bool {
+	if len(r.Resources) == 0 {
+		return true
+	}
+	for _, ref := range refs {
+		// "all" targets every kind, so it matches any rule
+		if ref.Kind == utils.ALL_RESOURCES {
+			return true
+		}
+		for _, kind := range r.Resources {
+			if utils.NormalizeResourceKind(kind) == ref.Kind {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isMoreSpecificThan returns true if r should win over other when both match the same command.
+func (r Rule) isMoreSpecificThan(other Rule) bool {
+	words, otherWords := len(strings.Fields(r.Command)), len(strings.Fields(other.Command))
+	if words != otherWords {
+		return words > otherWords
+	}
+	return r.conditionsCount() > other.conditionsCount()
+}
+
+func (r Rule) conditionsCount() int {
+	count := len(r.Flags)
+	if len(r.Resources) > 0 {
+		count++
+	}
+	if r.When != "" {
+		count++
+	}
+	return count
+}
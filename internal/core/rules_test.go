@@ -0,0 +1,209 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/telemaco019/kubesafe/internal/utils"
+	"gotest.tools/assert"
+)
+
+func TestContextConf_Match(t *testing.T) {
+	contextConf := NewContextConf("test", []string{"apply"})
+	contextConf.Rules = []Rule{
+		NewRule("delete", "namespace", "pvc", "crd", "node"),
+		NewRule("rollout restart", "deploy"),
+	}
+	testCases := []struct {
+		name     string
+		args     []string
+		wantRule string
+		wantOk   bool
+	}{
+		{
+			name:     "Protected command",
+			args:     []string{"apply", "-f", "x.yaml"},
+			wantRule: "apply",
+			wantOk:   true,
+		},
+		{
+			name:   "Resource not protected",
+			args:   []string{"delete", "pod", "x"},
+			wantOk: false,
+		},
+		{
+			name:     "Resource kind name form",
+			args:     []string{"-n", "prod", "delete", "namespace", "x"},
+			wantRule: "delete [namespace, pvc, crd, node]",
+			wantOk:   true,
+		},
+		{
+			name:     "Resource kind/name form with short name",
+			args:     []string{"delete", "po/x", "ns/y"},
+			wantRule: "delete [namespace, pvc, crd, node]",
+			wantOk:   true,
+		},
+		{
+			name:     "Comma-separated kinds",
+			args:     []string{"delete", "pod,persistentvolumeclaims", "x"},
+			wantRule: "delete [namespace, pvc, crd, node]",
+			wantOk:   true,
+		},
+		{
+			name:     "Resource with API group",
+			args:     []string{"delete", "customresourcedefinitions.apiextensions.k8s.io", "foos.example.com"},
+			wantRule: "delete [namespace, pvc, crd, node]",
+			wantOk:   true,
+		},
+		{
+			name:     "All resources",
+			args:     []string{"delete", "all", "--all"},
+			wantRule: "delete [namespace, pvc, crd, node]",
+			wantOk:   true,
+		},
+		{
+			name:     "Multi-word command with resource",
+			args:     []string{"rollout", "restart", "deployments/x"},
+			wantRule: "rollout restart [deploy]",
+			wantOk:   true,
+		},
+		{
+			name:   "Multi-word command with other resource",
+			args:   []string{"rollout", "restart", "sts/x"},
+			wantOk: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, ok := contextConf.Match(utils.ParseArgs("kubectl", tc.args), nil)
+			assert.Equal(t, ok, tc.wantOk)
+			if tc.wantOk {
+				assert.Equal(t, rule.String(), tc.wantRule)
+			}
+		})
+	}
+}
+
+func TestRule_MatchesFlags(t *testing.T) {
+	testCases := []struct {
+		name string
+		rule Rule
+		args []string
+		want bool
+	}{
+		{
+			name: "Present",
+			rule: Rule{Command: "delete", Flags: []FlagCondition{{Name: "force"}}},
+			args: []string{"delete", "pod", "x", "--force"},
+			want: true,
+		},
+		{
+			name: "Present but disabled",
+			rule: Rule{Command: "delete", Flags: []FlagCondition{{Name: "force"}}},
+			args: []string{"delete", "pod", "x", "--force=false"},
+			want: false,
+		},
+		{
+			name: "Not present",
+			rule: Rule{Command: "delete", Flags: []FlagCondition{{Name: "force"}}},
+			args: []string{"delete", "pod", "x"},
+			want: false,
+		},
+		{
+			name: "Short flag form",
+			rule: Rule{Command: "delete", Flags: []FlagCondition{{Name: "--all-namespaces"}}},
+			args: []string{"delete", "pods", "-A", "--all"},
+			want: true,
+		},
+		{
+			name: "Rule written with short flag",
+			rule: Rule{Command: "delete", Flags: []FlagCondition{{Name: "-A"}}},
+			args: []string{"delete", "pods", "--all-namespaces", "--all"},
+			want: true,
+		},
+		{
+			name: "Equals with separate value",
+			rule: Rule{Command: "delete", Flags: []FlagCondition{{Name: "grace-period", Operator: FLAG_OP_EQUALS, Value: "0"}}},
+			args: []string{"delete", "pod", "x", "--grace-period", "0"},
+			want: true,
+		},
+		{
+			name: "Equals not matching",
+			rule: Rule{Command: "delete", Flags: []FlagCondition{{Name: "cascade", Operator: FLAG_OP_EQUALS, Value: "orphan"}}},
+			args: []string{"delete", "deploy", "x", "--cascade=foreground"},
+			want: false,
+		},
+		{
+			name: "Numeric comparison",
+			rule: Rule{Command: "scale", Flags: []FlagCondition{{Name: "replicas", Operator: FLAG_OP_LT, Value: "1"}}},
+			args: []string{"scale", "deploy/x", "--replicas=0"},
+			want: true,
+		},
+		{
+			name: "Numeric comparison not matching",
+			rule: Rule{Command: "scale", Flags: []FlagCondition{{Name: "replicas", Operator: FLAG_OP_LT, Value: "1"}}},
+			args: []string{"scale", "deploy/x", "--replicas", "3"},
+			want: false,
+		},
+		{
+			name: "All conditions must hold",
+			rule: Rule{Command: "delete", Flags: []FlagCondition{{Name: "force"}, {Name: "grace-period", Operator: FLAG_OP_EQUALS, Value: "0"}}},
+			args: []string{"delete", "pod", "x", "--force"},
+			want: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.rule.Matches(utils.ParseArgs("kubectl", tc.args)), tc.want)
+		})
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	assert.NilError(t, Rule{Command: "scale", Flags: []FlagCondition{{Name: "replicas", Operator: FLAG_OP_LTE, Value: "0"}}}.validate())
+	assert.ErrorContains(t, Rule{Command: ""}.validate(), "empty")
+	assert.ErrorContains(t, Rule{Command: "scale", Flags: []FlagCondition{{Name: "replicas", Operator: "foo"}}}.validate(), "unknown operator")
+	assert.ErrorContains(t, Rule{Command: "scale", Flags: []FlagCondition{{Name: "replicas", Operator: FLAG_OP_GT, Value: "x"}}}.validate(), "numeric")
+	assert.NilError(t, Rule{Command: "delete", Severity: SEVERITY_TYPED_CONFIRM}.validate())
+	assert.ErrorContains(t, Rule{Command: "delete", Severity: "block"}.validate(), "unknown severity")
+	assert.NilError(t, Rule{Command: "delete", Challenge: CHALLENGE_RESOURCE, Retries: 3}.validate())
+	assert.ErrorContains(t, Rule{Command: "delete", Challenge: "captcha"}.validate(), "unknown challenge")
+	assert.ErrorContains(t, Rule{Command: "delete", Retries: -1}.validate(), "negative")
+}
+
+func TestRule_MatchesManifests(t *testing.T) {
+	rule := NewRule("apply", "namespace", "crd")
+	args := utils.ParseArgs("kubectl", []string{"apply", "-f", "manifests/"})
+	assert.Equal(t, rule.Matches(args), false)
+
+	args.Manifests = []utils.ManifestObject{{Kind: "Deployment", Name: "a"}}
+	assert.Equal(t, rule.Matches(args), false)
+
+	args.Manifests = append(args.Manifests, utils.ManifestObject{Kind: "CustomResourceDefinition", Name: "foos.example.com"})
+	assert.Equal(t, rule.Matches(args), true)
+}
+
+func TestRule_MatchesIncompleteManifests(t *testing.T) {
+	args := utils.ParseArgs("kubectl", []string{"apply", "-k", "overlays/prod"})
+	args.ManifestsIncomplete = true
+	// Resource conditions are assumed to match the manifests that could not be inspected
+	assert.Equal(t, NewRule("apply", "namespace").Matches(args), true)
+	assert.Equal(t, NewRule("delete", "namespace").Matches(args), false)
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// STATS_PERIOD_DAY buckets the stats by day.
+	STATS_PERIOD_DAY = "day"
+	// STATS_PERIOD_WEEK buckets the stats by week, starting on Monday.
+	STATS_PERIOD_WEEK = "week"
+)
+
+var STATS_PERIODS = []string{
+	STATS_PERIOD_DAY,
+	STATS_PERIOD_WEEK,
+}
+
+func ValidateStatsPeriod(period string) error {
+	if period != "" && !slices.Contains(STATS_PERIODS, period) {
+		return fmt.Errorf("unknown period %q, must be one of %s", period, strings.Join(STATS_PERIODS, ", "))
+	}
+	return nil
+}
+
+// PeriodStart returns the start of the day or of the week containing t, in the provided location.
+func PeriodStart(t time.Time, period string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	if period == STATS_PERIOD_WEEK {
+		// Weekday is 0 on Sunday
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+	}
+	return start
+}
+
+// StatsContext returns the context whose stats include the record: the matching safe context,
+// or the context of the command for the records written by older versions of kubesafe.
+func (r AuditRecord) StatsContext() string {
+	if r.SafeContext != "" {
+		return r.SafeContext
+	}
+	return r.Context
+}
+
+// StatsKey identifies the stats aggregated from the audit log.
+// Tool and Verb are empty unless the stats are broken down by tool and verb,
+// Period is zero unless the stats are bucketed by period.
+type StatsKey struct {
+	Context string
+	Tool    string
+	Verb    string
+	Period  time.Time
+}
+
+// AggregateStats counts the decisions recorded in the audit log by context, optionally breaking
+// them down by tool and verb, and bucketing them by day or week in the provided location.
+func AggregateStats(records []AuditRecord, breakdown bool, period string, loc *time.Location) map[StatsKey]*CommandStats {
+	res := make(map[StatsKey]*CommandStats)
+	for _, r := range records {
+		key := StatsKey{Context: r.StatsContext()}
+		if breakdown {
+			key.Tool = r.Tool
+			key.Verb = r.Verb()
+		}
+		if period != "" {
+			key.Period = PeriodStart(r.Time, period, loc)
+		}
+		if res[key] == nil {
+			res[key] = &CommandStats{}
+		}
+		res[key].record(r.Decision, r.Prompted, r.Time)
+	}
+	return res
+}
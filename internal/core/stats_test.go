@@ -0,0 +1,150 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestPeriodStart(t *testing.T) {
+	// Wednesday
+	now := time.Date(2025, 1, 29, 15, 30, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		period   string
+		loc      *time.Location
+		expected time.Time
+	}{
+		{
+			name:     "Day",
+			period:   STATS_PERIOD_DAY,
+			loc:      time.UTC,
+			expected: time.Date(2025, 1, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Week starts on Monday",
+			period:   STATS_PERIOD_WEEK,
+			loc:      time.UTC,
+			expected: time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Day in another location",
+			period:   STATS_PERIOD_DAY,
+			loc:      time.FixedZone("UTC+10", 10*60*60),
+			expected: time.Date(2025, 1, 30, 0, 0, 0, 0, time.FixedZone("UTC+10", 10*60*60)),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := PeriodStart(now, tc.period, tc.loc)
+			assert.Assert(t, res.Equal(tc.expected), "expected %v, got %v", tc.expected, res)
+		})
+	}
+
+	t.Run("Sunday belongs to the previous week", func(t *testing.T) {
+		sunday := time.Date(2025, 2, 2, 23, 0, 0, 0, time.UTC)
+		res := PeriodStart(sunday, STATS_PERIOD_WEEK, time.UTC)
+		assert.Assert(t, res.Equal(time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC)))
+	})
+}
+
+func TestAggregateStats(t *testing.T) {
+	monday := time.Date(2025, 1, 27, 10, 0, 0, 0, time.UTC)
+	records := []AuditRecord{
+		{
+			Time:     monday,
+			Context:  "prod",
+			Tool:     "kubectl",
+			Command:  "kubectl",
+			Args:     []string{"delete", "pod", "x"},
+			Decision: DECISION_CANCELED,
+			Prompted: true,
+		},
+		{
+			Time:     monday.AddDate(0, 0, 1),
+			Context:  "prod",
+			Tool:     "kubectl",
+			Command:  "kubectl",
+			Args:     []string{"apply", "-f", "x"},
+			Decision: DECISION_APPROVED,
+			Prompted: true,
+		},
+		{
+			Time:     monday.AddDate(0, 0, 7),
+			Context:  "prod",
+			Tool:     "kubectl",
+			Command:  "kubectl",
+			Args:     []string{"delete", "pod", "y"},
+			Decision: DECISION_DENIED,
+		},
+		{
+			Time:     monday,
+			Context:  "dev",
+			Tool:     "helm",
+			Command:  "helm",
+			Args:     []string{"uninstall", "x"},
+			Decision: DECISION_ALLOWED,
+		},
+	}
+
+	t.Run("By context", func(t *testing.T) {
+		res := AggregateStats(records, false, "", time.UTC)
+		assert.Equal(t, len(res), 2)
+		assert.DeepEqual(t, *res[StatsKey{Context: "prod"}], CommandStats{
+			PromptedCount:  2,
+			ConfirmedCount: 1,
+			CanceledCount:  1,
+			DeniedCount:    1,
+			FirstSeen:      monday,
+			LastSeen:       monday.AddDate(0, 0, 7),
+		})
+		assert.Equal(t, res[StatsKey{Context: "dev"}].AllowedCount, uint(1))
+	})
+
+	t.Run("By safe context", func(t *testing.T) {
+		records := []AuditRecord{
+			{Time: monday, Context: "prod-eu", SafeContext: "prod-.*", Decision: DECISION_CANCELED},
+			{Time: monday, Context: "prod-us", SafeContext: "prod-.*", Decision: DECISION_DENIED},
+			// Records of older versions of kubesafe don't include the safe context
+			{Time: monday, Context: "prod-eu", Decision: DECISION_APPROVED},
+		}
+		res := AggregateStats(records, false, "", time.UTC)
+		assert.Equal(t, len(res), 2)
+		assert.Equal(t, res[StatsKey{Context: "prod-.*"}].CanceledCount, uint(1))
+		assert.Equal(t, res[StatsKey{Context: "prod-.*"}].DeniedCount, uint(1))
+		assert.Equal(t, res[StatsKey{Context: "prod-eu"}].ConfirmedCount, uint(1))
+	})
+
+	t.Run("By tool, verb and week", func(t *testing.T) {
+		res := AggregateStats(records, true, STATS_PERIOD_WEEK, time.UTC)
+		assert.Equal(t, len(res), 4)
+		firstWeek := StatsKey{
+			Context: "prod",
+			Tool:    "kubectl",
+			Verb:    "delete",
+			Period:  time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC),
+		}
+		secondWeek := firstWeek
+		secondWeek.Period = firstWeek.Period.AddDate(0, 0, 7)
+		assert.Equal(t, res[firstWeek].CanceledCount, uint(1))
+		assert.Equal(t, res[secondWeek].DeniedCount, uint(1))
+	})
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repositories
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/telemaco019/kubesafe/internal/core"
+	"github.com/telemaco019/kubesafe/internal/utils"
+)
+
+// MAX_AUDIT_RECORD_SIZE is the maximum size of a line of the audit log, in bytes
+const MAX_AUDIT_RECORD_SIZE = 1024 * 1024
+
+// FileAuditLog is an append-only audit log, storing a JSON record per line.
+type FileAuditLog struct {
+	path     string
+	mode     os.FileMode
+	redactor *utils.Redactor
+}
+
+// GetAuditLog returns the audit log configured in the provided settings,
+// or nil if the audit log is disabled.
+func (r *FileSystemRepository) GetAuditLog(settings *core.Settings) (*FileAuditLog, error) {
+	conf := settings.Audit
+	if conf.Disabled {
+		return nil, nil
+	}
+	mode, err := conf.GetFileMode()
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := settings.Redaction.Redactor()
+	if err != nil {
+		return nil, err
+	}
+	logPath := r.auditLogPath
+	if conf.Path != "" {
+		logPath = conf.Path
+	}
+	if strings.HasPrefix(logPath, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		logPath = path.Join(homeDir, logPath[2:])
+	}
+	if logPath == "" {
+		return nil, fmt.Errorf("audit log path not configured")
+	}
+	return &FileAuditLog{path: logPath, mode: mode, redactor: redactor}, nil
+}
+
+func (l *FileAuditLog) Path() string {
+	return l.path
+}
+
+// auditLogHead is the last record of the audit log, stored in a separate file so
+// that removing records from the end of the audit log can be detected.
+type auditLogHead struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+func (l *FileAuditLog) headPath() string {
+	return l.path + ".head"
+}
+
+func (l *FileAuditLog) readHead() (*auditLogHead, error) {
+	content, err := os.ReadFile(l.headPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading audit log head: %w", err)
+	}
+	var head auditLogHead
+	if err = json.Unmarshal(content, &head); err != nil {
+		return nil, fmt.Errorf("invalid audit log head: %w", err)
+	}
+	return &head, nil
+}
+
+func (l *FileAuditLog) writeHead(head auditLogHead) error {
+	content, err := json.Marshal(head)
+	if err != nil {
+		return err
+	}
+	// Write the head atomically, so that it is never left half-written
+	if err = writeFileAtomic(l.headPath(), content, l.mode); err != nil {
+		return fmt.Errorf("error writing audit log head: %w", err)
+	}
+	return nil
+}
+
+// open opens the audit log and locks it, so that records are chained
+// consistently by concurrent kubesafe processes.
+func (l *FileAuditLog) open(flag int) (*os.File, func(), error) {
+	f, err := os.OpenFile(l.path, flag, l.mode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening audit log: %w", err)
+	}
+	if err = lockFile(f); err != nil {
+		_ = f.Close()
+		return nil, nil, fmt.Errorf("error locking audit log: %w", err)
+	}
+	return f, func() {
+		_ = unlockFile(f)
+		_ = f.Close()
+	}, nil
+}
+
+// Append writes the provided record at the end of the audit log, redacting the
+// secrets in its arguments and chaining it to the previous record.
+func (l *FileAuditLog) Append(record core.AuditRecord) error {
+	slog.Debug("Writing audit record", "path", l.path)
+	f, release, err := l.open(os.O_RDWR | os.O_APPEND | os.O_CREATE)
+	if err != nil {
+		return err
+	}
+	defer release()
+	// Enforce the configured permissions, which are masked by umask on creation
+	if err = f.Chmod(l.mode); err != nil {
+		return fmt.Errorf("error setting audit log permissions: %w", err)
+	}
+
+	lastLine, err := readLastLine(f)
+	if err != nil {
+		return fmt.Errorf("error reading audit log: %w", err)
+	}
+	record.Seq = 1
+	record.PrevHash = ""
+	if len(lastLine) > 0 {
+		last, err := core.ParseAuditRecord(lastLine)
+		if err != nil {
+			return fmt.Errorf("error reading the last audit record: %w", err)
+		}
+		record.Seq = last.Seq + 1
+		record.PrevHash = last.Hash
+	}
+	record.Args = l.redactor.RedactArgs(record.Command, record.Args)
+	line, hash, err := record.Seal()
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing audit log: %w", err)
+	}
+	return l.writeHead(auditLogHead{Seq: record.Seq, Hash: hash})
+}
+
+// Verify checks that the records of the audit log are chained and match their
+// hashes, and that the last record is the one the audit log is expected to end with.
+// It returns the number of records in the audit log.
+func (l *FileAuditLog) Verify() (uint64, error) {
+	f, release, err := l.open(os.O_RDONLY)
+	if errors.Is(err, os.ErrNotExist) {
+		// No records have been written yet, unless the whole audit log was removed
+		head, err := l.readHead()
+		if err == nil && head != nil {
+			return 0, fmt.Errorf("audit log not found, but it should end with record %d", head.Seq)
+		}
+		return 0, err
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	var last core.AuditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), MAX_AUDIT_RECORD_SIZE)
+	for line := 1; scanner.Scan(); line++ {
+		record, err := core.ParseAuditRecord(scanner.Bytes())
+		if err != nil {
+			return last.Seq, fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.Seq != last.Seq+1 {
+			return last.Seq, fmt.Errorf(
+				"line %d: expected record %d, found record %d: records were removed or reordered",
+				line,
+				last.Seq+1,
+				record.Seq,
+			)
+		}
+		if record.PrevHash != last.Hash {
+			return last.Seq, fmt.Errorf("line %d: record %d is not chained to the previous record", line, record.Seq)
+		}
+		last = record
+	}
+	if err = scanner.Err(); err != nil {
+		return last.Seq, fmt.Errorf("error reading audit log: %w", err)
+	}
+
+	head, err := l.readHead()
+	if err != nil {
+		return last.Seq, err
+	}
+	if head == nil {
+		if last.Seq > 0 {
+			return last.Seq, fmt.Errorf("audit log head %q not found", l.headPath())
+		}
+		return 0, nil
+	}
+	if head.Seq != last.Seq || head.Hash != last.Hash {
+		return last.Seq, fmt.Errorf(
+			"audit log ends with record %d, but it should end with record %d: records were removed",
+			last.Seq,
+			head.Seq,
+		)
+	}
+	return last.Seq, nil
+}
+
+// Records returns the records of the audit log, from the oldest to the newest.
+// Unlike Verify, it doesn't check the hash chain of the records.
+func (l *FileAuditLog) Records() ([]core.AuditRecord, error) {
+	res := make([]core.AuditRecord, 0)
+	f, release, err := l.open(os.O_RDONLY)
+	if errors.Is(err, os.ErrNotExist) {
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), MAX_AUDIT_RECORD_SIZE)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record core.AuditRecord
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: invalid record: %w", line, err)
+		}
+		res = append(res, record)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading audit log: %w", err)
+	}
+	return res, nil
+}
+
+// readLastLine returns the last line of the provided file, without the trailing newline.
+func readLastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	res := make([]byte, 0)
+	chunk := make([]byte, 4096)
+	for offset := size; offset > 0; {
+		n := int64(len(chunk))
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+		if _, err = f.ReadAt(chunk[:n], offset); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		res = append(bytes.Clone(chunk[:n]), res...)
+		trimmed := bytes.TrimRight(res, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+		if len(res) > MAX_AUDIT_RECORD_SIZE {
+			return nil, fmt.Errorf("audit record exceeds %d bytes", MAX_AUDIT_RECORD_SIZE)
+		}
+	}
+	return bytes.TrimRight(res, "\n"), nil
+}
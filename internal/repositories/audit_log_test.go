@@ -0,0 +1,222 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package repositories
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/telemaco019/kubesafe/internal/core"
+)
+
+func TestFileAuditLog_Append(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		repo := &FileSystemRepository{auditLogPath: filepath.Join(t.TempDir(), "audit.jsonl")}
+		auditLog, err := repo.GetAuditLog(&core.Settings{Audit: core.AuditConf{FileMode: "0640"}})
+		assert.NoError(t, err)
+		exitCode := 1
+		records := []core.AuditRecord{
+			{Context: "prod", Args: []string{"delete", "pod", "x"}, Decision: core.DECISION_CANCELED},
+			{Context: "prod", Args: []string{"delete", "pod", "y"}, Decision: core.DECISION_APPROVED, ExitCode: &exitCode},
+		}
+		for _, record := range records {
+			assert.NoError(t, auditLog.Append(record))
+		}
+		// Verify permissions
+		info, err := os.Stat(auditLog.Path())
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+		// Verify there is a record per line
+		f, err := os.Open(auditLog.Path())
+		assert.NoError(t, err)
+		defer func() { _ = f.Close() }()
+		loaded := make([]core.AuditRecord, 0)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			record, err := core.ParseAuditRecord(scanner.Bytes())
+			assert.NoError(t, err)
+			loaded = append(loaded, record)
+		}
+		assert.Len(t, loaded, len(records))
+		for i, record := range loaded {
+			assert.Equal(t, uint64(i+1), record.Seq)
+			assert.Equal(t, records[i].Args, record.Args)
+			assert.Equal(t, records[i].Decision, record.Decision)
+			assert.Equal(t, records[i].ExitCode, record.ExitCode)
+		}
+		// Records are chained
+		assert.Equal(t, "", loaded[0].PrevHash)
+		assert.Equal(t, loaded[0].Hash, loaded[1].PrevHash)
+	})
+
+	t.Run("Secrets are redacted", func(t *testing.T) {
+		repo := &FileSystemRepository{auditLogPath: filepath.Join(t.TempDir(), "audit.jsonl")}
+		auditLog, err := repo.GetAuditLog(&core.Settings{Redaction: core.RedactionConf{Flags: []string{"db-password"}}})
+		assert.NoError(t, err)
+		record := core.AuditRecord{
+			Command: "kubectl",
+			Args:    []string{"create", "secret", "generic", "x", "--from-literal=password=y", "--db-password", "z"},
+		}
+		assert.NoError(t, auditLog.Append(record))
+		content, err := os.ReadFile(auditLog.Path())
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), `"--from-literal=password=***","--db-password","***"`)
+		assert.NotContains(t, string(content), "password=y")
+	})
+
+	t.Run("Custom path", func(t *testing.T) {
+		repo := &FileSystemRepository{auditLogPath: "/default/audit.jsonl"}
+		auditLog, err := repo.GetAuditLog(&core.Settings{Audit: core.AuditConf{Path: "/var/log/kubesafe.jsonl"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "/var/log/kubesafe.jsonl", auditLog.Path())
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		repo := &FileSystemRepository{auditLogPath: "/default/audit.jsonl"}
+		auditLog, err := repo.GetAuditLog(&core.Settings{Audit: core.AuditConf{Disabled: true}})
+		assert.NoError(t, err)
+		assert.Nil(t, auditLog)
+	})
+
+	t.Run("Invalid file mode", func(t *testing.T) {
+		repo := &FileSystemRepository{auditLogPath: "/default/audit.jsonl"}
+		_, err := repo.GetAuditLog(&core.Settings{Audit: core.AuditConf{FileMode: "rw-r--r--"}})
+		assert.Error(t, err)
+	})
+}
+
+func newTestAuditLog(t *testing.T, records int) *FileAuditLog {
+	repo := &FileSystemRepository{auditLogPath: filepath.Join(t.TempDir(), "audit.jsonl")}
+	auditLog, err := repo.GetAuditLog(&core.Settings{})
+	assert.NoError(t, err)
+	for i := 0; i < records; i++ {
+		err = auditLog.Append(core.AuditRecord{
+			Context:  "prod",
+			Command:  "kubectl",
+			Args:     []string{"delete", "pod", "x"},
+			Decision: core.DECISION_APPROVED,
+		})
+		assert.NoError(t, err)
+	}
+	return auditLog
+}
+
+func TestFileAuditLog_Verify(t *testing.T) {
+	// tamper rewrites the lines of the audit log with the provided function
+	tamper := func(t *testing.T, auditLog *FileAuditLog, f func(lines []string) []string) {
+		content, err := os.ReadFile(auditLog.Path())
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+		lines = f(lines)
+		err = os.WriteFile(auditLog.Path(), []byte(strings.Join(lines, "\n")+"\n"), 0600)
+		assert.NoError(t, err)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		auditLog := newTestAuditLog(t, 3)
+		count, err := auditLog.Verify()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(3), count)
+	})
+
+	t.Run("Empty audit log", func(t *testing.T) {
+		auditLog := newTestAuditLog(t, 0)
+		count, err := auditLog.Verify()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(0), count)
+	})
+
+	t.Run("Edited record", func(t *testing.T) {
+		auditLog := newTestAuditLog(t, 3)
+		tamper(t, auditLog, func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], `"approved"`, `"denied"`, 1)
+			return lines
+		})
+		_, err := auditLog.Verify()
+		assert.ErrorContains(t, err, "does not match its hash")
+	})
+
+	t.Run("Removed record", func(t *testing.T) {
+		auditLog := newTestAuditLog(t, 3)
+		tamper(t, auditLog, func(lines []string) []string {
+			return append(lines[:1], lines[2:]...)
+		})
+		_, err := auditLog.Verify()
+		assert.ErrorContains(t, err, "removed or reordered")
+	})
+
+	t.Run("Reordered records", func(t *testing.T) {
+		auditLog := newTestAuditLog(t, 3)
+		tamper(t, auditLog, func(lines []string) []string {
+			lines[0], lines[1] = lines[1], lines[0]
+			return lines
+		})
+		_, err := auditLog.Verify()
+		assert.ErrorContains(t, err, "removed or reordered")
+	})
+
+	t.Run("Truncated audit log", func(t *testing.T) {
+		auditLog := newTestAuditLog(t, 3)
+		tamper(t, auditLog, func(lines []string) []string {
+			return lines[:2]
+		})
+		_, err := auditLog.Verify()
+		assert.ErrorContains(t, err, "should end with record 3")
+	})
+
+	t.Run("Removed audit log", func(t *testing.T) {
+		auditLog := newTestAuditLog(t, 3)
+		assert.NoError(t, os.Remove(auditLog.Path()))
+		_, err := auditLog.Verify()
+		assert.ErrorContains(t, err, "not found")
+	})
+}
+
+func TestFileAuditLog_Records(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		auditLog := newTestAuditLog(t, 3)
+		records, err := auditLog.Records()
+		assert.NoError(t, err)
+		assert.Len(t, records, 3)
+		for i, record := range records {
+			assert.Equal(t, uint64(i+1), record.Seq)
+			assert.Equal(t, "prod", record.Context)
+		}
+	})
+
+	t.Run("Missing audit log", func(t *testing.T) {
+		auditLog := newTestAuditLog(t, 0)
+		records, err := auditLog.Records()
+		assert.NoError(t, err)
+		assert.Empty(t, records)
+	})
+
+	t.Run("Invalid record", func(t *testing.T) {
+		auditLog := newTestAuditLog(t, 1)
+		f, err := os.OpenFile(auditLog.Path(), os.O_APPEND|os.O_WRONLY, 0600)
+		assert.NoError(t, err)
+		_, err = f.WriteString("not a record\n")
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+		_, err = auditLog.Records()
+		assert.ErrorContains(t, err, "line 2: invalid record")
+	})
+}
@@ -17,6 +17,7 @@
 package repositories
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -29,6 +30,10 @@ import (
 
 type FileSystemRepository struct {
 	configFilePath string
+	// statsFilePath is the path of the stats of the safe contexts
+	statsFilePath string
+	// auditLogPath is the default path of the audit log
+	auditLogPath string
 }
 
 func NewFileSystemRepository() (*FileSystemRepository, error) {
@@ -46,6 +51,8 @@ func NewFileSystemRepository() (*FileSystemRepository, error) {
 	if exists {
 		return &FileSystemRepository{
 			configFilePath: legacyPath,
+			statsFilePath:  path.Join(homeDir, ".kubesafe-stats.yaml"),
+			auditLogPath:   path.Join(homeDir, ".kubesafe-audit.jsonl"),
 		}, nil
 	}
 
@@ -67,6 +74,8 @@ func NewFileSystemRepository() (*FileSystemRepository, error) {
 	}
 	return &FileSystemRepository{
 		configFilePath: path.Join(kubesafeDir, "config.yaml"),
+		statsFilePath:  path.Join(kubesafeDir, "stats.yaml"),
+		auditLogPath:   path.Join(kubesafeDir, "audit.jsonl"),
 	}, nil
 }
 
@@ -76,7 +85,9 @@ func (r *FileSystemRepository) SaveSettings(settings core.Settings) error {
 	if err != nil {
 		return fmt.Errorf("error marshalling settings: %w", err)
 	}
-	err = os.WriteFile(r.configFilePath, settingsFile, 0644)
+	err = withLock(r.configFilePath, func() error {
+		return writeFileAtomic(r.configFilePath, settingsFile, 0644)
+	})
 	if err != nil {
 		return fmt.Errorf("error writing settings file: %w", err)
 	}
@@ -105,5 +116,51 @@ func (r *FileSystemRepository) LoadSettings() (*core.Settings, error) {
 		return nil, fmt.Errorf("error unmarshalling settings file: %w", err)
 	}
 	res := core.NewSettings(settings.Contexts...)
+	res.PreHooks = settings.PreHooks
+	res.Audit = settings.Audit
+	res.Redaction = settings.Redaction
+	if err = res.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid settings file: %w", err)
+	}
 	return &res, nil
 }
+
+// LoadStats returns the stats of the safe contexts. If the stats file does not exist yet,
+// the stats stored in the settings by older versions of kubesafe are returned.
+func (r *FileSystemRepository) LoadStats(settings *core.Settings) (*core.Stats, error) {
+	slog.Debug("Loading stats", "path", r.statsFilePath)
+	content, err := os.ReadFile(r.statsFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		stats := core.NewStats(settings.Contexts...)
+		return &stats, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading stats file: %w", err)
+	}
+	stats := core.NewStats()
+	if err = yaml.Unmarshal(content, &stats); err != nil {
+		return nil, fmt.Errorf("error unmarshalling stats file: %w", err)
+	}
+	return &stats, nil
+}
+
+// UpdateStats applies the provided update to the stats of the safe contexts,
+// holding a lock so that the updates of concurrent kubesafe processes are not lost.
+func (r *FileSystemRepository) UpdateStats(settings *core.Settings, update func(stats *core.Stats)) error {
+	slog.Debug("Updating stats", "path", r.statsFilePath)
+	return withLock(r.statsFilePath, func() error {
+		stats, err := r.LoadStats(settings)
+		if err != nil {
+			return err
+		}
+		update(stats)
+		content, err := yaml.Marshal(stats)
+		if err != nil {
+			return fmt.Errorf("error marshalling stats: %w", err)
+		}
+		if err = writeFileAtomic(r.statsFilePath, content, 0644); err != nil {
+			return fmt.Errorf("error writing stats file: %w", err)
+		}
+		return nil
+	})
+}
@@ -16,7 +16,11 @@
 package repositories
 
 import (
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/telemaco019/kubesafe/internal/core"
@@ -45,28 +49,62 @@ func newTestFsRepository() *FileSystemRepository {
 	}
 }
 
-func TestSettingsRepository_UpdateContextStats(t *testing.T) {
+func TestFileSystemRepository_UpdateStats(t *testing.T) {
+	newRepo := func(t *testing.T) *FileSystemRepository {
+		dir := t.TempDir()
+		return &FileSystemRepository{
+			configFilePath: filepath.Join(dir, "config.yaml"),
+			statsFilePath:  filepath.Join(dir, "stats.yaml"),
+		}
+	}
+
 	t.Run("Success", func(t *testing.T) {
-		repo := newTestFsRepository()
+		repo := newRepo(t)
 		settings := newSettings("context1", "context2")
-		// Save
-		err := repo.SaveSettings(settings)
+		err := repo.UpdateStats(&settings, func(stats *core.Stats) {
+			stats.Get("context1").Record("kubectl", "delete", core.DECISION_CANCELED, true, time.Now())
+		})
 		assert.NoError(t, err)
-		// Update stats
-		loadedSettings, err := repo.LoadSettings()
+		stats, err := repo.LoadStats(&settings)
 		assert.NoError(t, err)
-		contextConf, found := loadedSettings.GetContextConf("context1")
-		assert.True(t, found)
-		assert.Equal(t, uint(0), contextConf.Stats.CanceledCount)
-		contextConf.Stats.CanceledCount += 1
-		err = repo.SaveSettings(*loadedSettings)
+		assert.Equal(t, uint(1), stats.Get("context1").CanceledCount)
+		assert.Equal(t, uint(0), stats.Get("context2").CanceledCount)
+		// The settings file is not rewritten
+		_, err = os.Stat(repo.configFilePath)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("Stats of older versions are migrated", func(t *testing.T) {
+		repo := newRepo(t)
+		settings := newSettings("context1")
+		settings.Contexts[0].Stats = &core.ContextStats{CommandStats: core.CommandStats{CanceledCount: 3}}
+		err := repo.UpdateStats(&settings, func(stats *core.Stats) {
+			stats.Get("context1").Record("kubectl", "delete", core.DECISION_CANCELED, true, time.Now())
+		})
+		assert.NoError(t, err)
+		stats, err := repo.LoadStats(&settings)
 		assert.NoError(t, err)
-		// Load and verify
-		updatedSettings, err := repo.LoadSettings()
+		assert.Equal(t, uint(4), stats.Get("context1").CanceledCount)
+	})
+
+	t.Run("Concurrent updates are not lost", func(t *testing.T) {
+		repo := newRepo(t)
+		settings := newSettings("context1")
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := repo.UpdateStats(&settings, func(stats *core.Stats) {
+					stats.Get("context1").Record("kubectl", "delete", core.DECISION_APPROVED, true, time.Now())
+				})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		stats, err := repo.LoadStats(&settings)
 		assert.NoError(t, err)
-		updatedContextConf, found := updatedSettings.GetContextConf("context1")
-		assert.True(t, found)
-		assert.Equal(t, uint(1), updatedContextConf.Stats.CanceledCount)
+		assert.Equal(t, uint(20), stats.Get("context1").ConfirmedCount)
 	})
 }
 
@@ -0,0 +1,454 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ToolSpec describes how the command line of a wrapped tool is parsed.
+type ToolSpec struct {
+	// ValueFlags contains the long names of the flags that take a value.
+	ValueFlags map[string]struct{}
+	// ShortFlags maps short flag names to their long counterparts.
+	ShortFlags map[string]string
+	// VerbShortFlags maps short flag names to their long counterparts for specific verbs,
+	// for short flags with a different meaning depending on the verb (e.g. "kubectl logs -p").
+	VerbShortFlags map[string]map[string]string
+	// PreviewCommands are the commands that only show what would change,
+	// without changing anything (e.g. "kubectl diff", "helm template").
+	PreviewCommands []string
+	// DryRunCommands are the commands that honor the --dry-run flag. The flag
+	// is ignored for any other command, e.g. plugins that may not implement it.
+	DryRunCommands []string
+}
+
+func newToolSpec(shortFlags map[string]string, previewCommands []string, valueFlags ...[]string) ToolSpec {
+	spec := ToolSpec{
+		ValueFlags:      make(map[string]struct{}),
+		ShortFlags:      shortFlags,
+		PreviewCommands: previewCommands,
+	}
+	for _, flags := range valueFlags {
+		for _, flag := range flags {
+			spec.ValueFlags[flag] = struct{}{}
+		}
+	}
+	return spec
+}
+
+func (s ToolSpec) withVerbShortFlags(verbShortFlags map[string]map[string]string) ToolSpec {
+	s.VerbShortFlags = verbShortFlags
+	return s
+}
+
+func (s ToolSpec) withDryRunCommands(commands ...string) ToolSpec {
+	s.DryRunCommands = commands
+	return s
+}
+
+// Global flags shared by kubectl and any tool built on top of client-go.
+var kubeGlobalValueFlags = []string{
+	"as",
+	"as-group",
+	"as-uid",
+	"cache-dir",
+	"certificate-authority",
+	"client-certificate",
+	"client-key",
+	"cluster",
+	"context",
+	"kubeconfig",
+	"log-dir",
+	"log-file",
+	"log-file-max-size",
+	"log-flush-frequency",
+	"namespace",
+	"password",
+	"profile",
+	"profile-output",
+	"request-timeout",
+	"server",
+	"tls-server-name",
+	"token",
+	"user",
+	"username",
+	"v",
+	"vmodule",
+}
+
+var kubectlValueFlags = []string{
+	"chunk-size",
+	"container",
+	"field-manager",
+	"field-selector",
+	"filename",
+	"for",
+	"from-env-file",
+	"from-file",
+	"from-literal",
+	"grace-period",
+	"image",
+	"kustomize",
+	"limits",
+	"output",
+	"overrides",
+	"patch",
+	"pod",
+	"port",
+	"prune-allowlist",
+	"raw",
+	"replicas",
+	"requests",
+	"selector",
+	"since",
+	"sort-by",
+	"subresource",
+	"tail",
+	"template",
+	"timeout",
+	"to-revision",
+	"type",
+}
+
+var helmValueFlags = []string{
+	"api-versions",
+	"burst-limit",
+	"ca-file",
+	"cascade",
+	"cert-file",
+	"description",
+	"history-max",
+	"key-file",
+	"keyring",
+	"kube-apiserver",
+	"kube-as-group",
+	"kube-as-user",
+	"kube-ca-file",
+	"kube-context",
+	"kube-tls-server-name",
+	"kube-token",
+	"kube-version",
+	"kubeconfig",
+	"max",
+	"name-template",
+	"namespace",
+	"output",
+	"output-dir",
+	"password",
+	"post-renderer",
+	"post-renderer-args",
+	"qps",
+	"registry-config",
+	"repo",
+	"repository-cache",
+	"repository-config",
+	"revision",
+	"selector",
+	"set",
+	"set-file",
+	"set-json",
+	"set-literal",
+	"set-string",
+	"show-only",
+	"timeout",
+	"username",
+	"values",
+	"version",
+}
+
+// kubectlVerbShortFlags are the short flags of kubectl whose meaning depends on the verb
+var kubectlVerbShortFlags = map[string]map[string]string{
+	"patch":        {"p": "patch"},
+	"logs":         {"p": "previous"},
+	"exec":         {"p": "pod"},
+	"port-forward": {"p": "pod"},
+}
+
+var toolSpecs = map[string]ToolSpec{
+	"kubectl": newToolSpec(
+		map[string]string{
+			"A": "all-namespaces",
+			"R": "recursive",
+			"c": "container",
+			"f": "filename",
+			"i": "stdin",
+			"k": "kustomize",
+			"l": "selector",
+			"n": "namespace",
+			"o": "output",
+			"s": "server",
+			"t": "tty",
+			"v": "v",
+		},
+		[]string{"diff"},
+		kubeGlobalValueFlags,
+		kubectlValueFlags,
+	).withVerbShortFlags(kubectlVerbShortFlags).withDryRunCommands(
+		"annotate",
+		"apply",
+		"auth reconcile",
+		"autoscale",
+		"cordon",
+		"create",
+		"delete",
+		"drain",
+		"expose",
+		"label",
+		"patch",
+		"replace",
+		"rollout undo",
+		"run",
+		"scale",
+		"set",
+		"taint",
+		"uncordon",
+	),
+	"argocd": newToolSpec(
+		map[string]string{
+			"n": "namespace",
+		},
+		[]string{"app diff", "app manifests"},
+		[]string{"kube-context", "namespace", "server"},
+	).withDryRunCommands("app sync"),
+	"flux": newToolSpec(
+		map[string]string{
+			"n": "namespace",
+		},
+		[]string{"diff"},
+		kubeGlobalValueFlags,
+	),
+	"helm": newToolSpec(
+		map[string]string{
+			"A": "all-namespaces",
+			"a": "api-versions",
+			"f": "values",
+			"l": "selector",
+			"n": "namespace",
+			"o": "output",
+			"s": "show-only",
+		},
+		[]string{"template", "lint", "diff"},
+		helmValueFlags,
+	).withDryRunCommands("install", "upgrade", "uninstall", "rollback"),
+}
+
+// Tools that share the command line of another tool.
+var toolAliases = map[string]string{
+	"kubecolor": "kubectl",
+	"oc":        "kubectl",
+}
+
+// Spec used for any tool kubesafe does not know about.
+var defaultToolSpec = newToolSpec(
+	map[string]string{
+		"n": "namespace",
+		"s": "server",
+	},
+	nil,
+	kubeGlobalValueFlags,
+	[]string{"kube-context"},
+)
+
+// CommandName returns the normalized basename of cmd, without resolving
+// aliases, e.g. "/usr/local/bin/oc.exe" -> "oc".
+func CommandName(cmd string) string {
+	name := strings.ToLower(filepath.Base(cmd))
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// ToolName returns the normalized name of the tool invoked by cmd,
+// e.g. "/usr/local/bin/kubectl.exe" -> "kubectl", "oc" -> "kubectl".
+func ToolName(cmd string) string {
+	name := CommandName(cmd)
+	if alias, ok := toolAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// GetToolSpec returns the spec used to parse the command line of the provided tool.
+func GetToolSpec(tool string) ToolSpec {
+	if spec, ok := toolSpecs[ToolName(tool)]; ok {
+		return spec
+	}
+	return defaultToolSpec
+}
+
+// LongFlagName returns the long name of the provided flag, which can be
+// given with or without dashes (e.g. "-A" -> "all-namespaces", "--force" -> "force").
+func (s ToolSpec) LongFlagName(flag string) string {
+	name := strings.TrimLeft(flag, "-")
+	if long, ok := s.ShortFlags[name]; ok && len(name) == 1 {
+		return long
+	}
+	return name
+}
+
+// ParsedArgs is the result of parsing the arguments of a wrapped command.
+type ParsedArgs struct {
+	// Command is the wrapped command as invoked by the user, e.g. "/usr/local/bin/oc".
+	Command string
+	// Tool is the normalized name of the wrapped tool.
+	Tool string
+	// Positionals contains the non-flag arguments in the order they appear,
+	// e.g. [delete pod my-pod] for "kubectl -n prod delete pod my-pod --force".
+	Positionals []string
+	// Flags maps the long name of each flag to the values it was given.
+	// Flags without a value are stored with an empty value.
+	Flags map[string][]string
+	// Manifests contains the objects defined in the manifests passed to the
+	// command (see LoadManifests). Empty unless explicitly set.
+	Manifests []ManifestObject
+	// ManifestsIncomplete is true if some of the manifests passed to the command could not
+	// be inspected, in which case Manifests may not include all the objects of the command.
+	ManifestsIncomplete bool
+}
+
+// ParseArgs parses the arguments of the wrapped command, skipping flags
+// (and their values) so that subcommands can be found regardless of where
+// global flags are placed.
+func ParseArgs(cmd string, args []string) *ParsedArgs {
+	spec := GetToolSpec(cmd)
+	res := &ParsedArgs{
+		Command:     cmd,
+		Tool:        ToolName(cmd),
+		Positionals: make([]string, 0),
+		Flags:       make(map[string][]string),
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			// Everything after "--" belongs to the command run by the tool (e.g. kubectl exec)
+			return res
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			if !hasValue {
+				if _, ok := spec.ValueFlags[name]; ok && i+1 < len(args) {
+					i++
+					value = args[i]
+				}
+			}
+			res.addFlag(name, value)
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			i += res.parseShortFlags(spec, arg[1:], args[i+1:])
+		default:
+			res.Positionals = append(res.Positionals, arg)
+		}
+	}
+	return res
+}
+
+// parseShortFlags parses a group of short flags (e.g. "it", "nprod") and
+// returns the number of following arguments that were consumed as values.
+func (p *ParsedArgs) parseShortFlags(spec ToolSpec, group string, next []string) int {
+	for i, r := range group {
+		short := string(r)
+		name, ok := spec.VerbShortFlags[p.Verb()][short]
+		if !ok {
+			name, ok = spec.ShortFlags[short]
+		}
+		if !ok {
+			name = short
+		}
+		if _, ok := spec.ValueFlags[name]; !ok {
+			p.addFlag(name, "")
+			continue
+		}
+		value := strings.TrimPrefix(group[i+len(short):], "=")
+		if value != "" {
+			p.addFlag(name, value)
+			return 0
+		}
+		if len(next) > 0 {
+			p.addFlag(name, next[0])
+			return 1
+		}
+		p.addFlag(name, "")
+		return 0
+	}
+	return 0
+}
+
+func (p *ParsedArgs) addFlag(name, value string) {
+	p.Flags[name] = append(p.Flags[name], value)
+}
+
+// Verb returns the subcommand invoked on the wrapped tool, or an empty string if there is none.
+func (p *ParsedArgs) Verb() string {
+	if len(p.Positionals) == 0 {
+		return ""
+	}
+	return p.Positionals[0]
+}
+
+// HasFlag returns true if the flag with the provided long name was set.
+func (p *ParsedArgs) HasFlag(name string) bool {
+	_, ok := p.Flags[name]
+	return ok
+}
+
+// GetFlag returns the last value given to the flag with the provided long name.
+func (p *ParsedArgs) GetFlag(name string) (string, bool) {
+	values, ok := p.Flags[name]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// isCommand returns true if the command starts with any of the provided commands (e.g. "rollout undo").
+func (p *ParsedArgs) isCommand(commands []string) bool {
+	for _, command := range commands {
+		words := strings.Fields(command)
+		if len(words) > len(p.Positionals) {
+			continue
+		}
+		if slices.Equal(p.Positionals[:len(words)], words) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDryRun returns true if the command is a dry-run (e.g. "kubectl apply --dry-run=server",
+// "helm upgrade --dry-run") or a preview command (e.g. "kubectl diff", "helm template").
+// The --dry-run flag is honored only for the commands known to implement it.
+func (p *ParsedArgs) IsDryRun() bool {
+	spec := GetToolSpec(p.Tool)
+	if p.isCommand(spec.PreviewCommands) {
+		return true
+	}
+	value, ok := p.GetFlag("dry-run")
+	return ok && isDryRunValue(value) && p.isCommand(spec.DryRunCommands)
+}
+
+// isDryRunValue returns true if the value of the --dry-run flag enables the dry-run, following
+// kubectl: "client", "server", no value or any true boolean (e.g. "1"). Any other value either
+// disables it (e.g. "none", "0", "False") or is rejected by the tool.
+func isDryRunValue(value string) bool {
+	switch value {
+	case "", "client", "server":
+		return true
+	}
+	enabled, err := strconv.ParseBool(value)
+	return err == nil && enabled
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToolName(t *testing.T) {
+	testCases := []struct {
+		cmd      string
+		expected string
+	}{
+		{cmd: "kubectl", expected: "kubectl"},
+		{cmd: "/usr/local/bin/helm", expected: "helm"},
+		{cmd: "kubectl.exe", expected: "kubectl"},
+		{cmd: "kubecolor", expected: "kubectl"},
+		{cmd: "flux", expected: "flux"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.cmd, func(t *testing.T) {
+			if result := ToolName(tc.cmd); result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	testCases := []struct {
+		name            string
+		cmd             string
+		args            []string
+		wantPositionals []string
+		wantFlags       map[string][]string
+	}{
+		{
+			name:            "No flags",
+			cmd:             "kubectl",
+			args:            []string{"delete", "pod", "x"},
+			wantPositionals: []string{"delete", "pod", "x"},
+			wantFlags:       map[string][]string{},
+		},
+		{
+			name:            "Helm api-versions short flag takes a value",
+			cmd:             "helm",
+			args:            []string{"-a", "batch/v1", "upgrade", "x", "c"},
+			wantPositionals: []string{"upgrade", "x", "c"},
+			wantFlags:       map[string][]string{"api-versions": {"batch/v1"}},
+		},
+		{
+			name:            "Kubectl -p is the patch of kubectl patch",
+			cmd:             "kubectl",
+			args:            []string{"patch", "deploy", "x", "-p", `{"spec":{}}`},
+			wantPositionals: []string{"patch", "deploy", "x"},
+			wantFlags:       map[string][]string{"patch": {`{"spec":{}}`}},
+		},
+		{
+			name:            "Kubectl -p is a boolean flag of kubectl logs",
+			cmd:             "kubectl",
+			args:            []string{"logs", "-p", "x"},
+			wantPositionals: []string{"logs", "x"},
+			wantFlags:       map[string][]string{"previous": {""}},
+		},
+		{
+			name:            "Short global flag before the verb",
+			cmd:             "kubectl",
+			args:            []string{"-n", "prod", "delete", "pod", "x"},
+			wantPositionals: []string{"delete", "pod", "x"},
+			wantFlags:       map[string][]string{"namespace": {"prod"}},
+		},
+		{
+			name:            "Long global flag before the verb",
+			cmd:             "kubectl",
+			args:            []string{"--context", "prod-eu", "delete", "ns", "x"},
+			wantPositionals: []string{"delete", "ns", "x"},
+			wantFlags:       map[string][]string{"context": {"prod-eu"}},
+		},
+		{
+			name:            "Flags with equal sign and attached short value",
+			cmd:             "kubectl",
+			args:            []string{"--context=prod", "-nkube-system", "delete", "--grace-period=0", "pod", "x"},
+			wantPositionals: []string{"delete", "pod", "x"},
+			wantFlags: map[string][]string{
+				"context":      {"prod"},
+				"namespace":    {"kube-system"},
+				"grace-period": {"0"},
+			},
+		},
+		{
+			name:            "Boolean flags do not consume the next argument",
+			cmd:             "helm",
+			args:            []string{"--debug", "uninstall", "foo"},
+			wantPositionals: []string{"uninstall", "foo"},
+			wantFlags:       map[string][]string{"debug": {""}},
+		},
+		{
+			name:            "Grouped short flags",
+			cmd:             "kubectl",
+			args:            []string{"exec", "-it", "pod", "--", "rm", "-rf", "/"},
+			wantPositionals: []string{"exec", "pod"},
+			wantFlags:       map[string][]string{"stdin": {""}, "tty": {""}},
+		},
+		{
+			name:            "Helm kube-context",
+			cmd:             "helm",
+			args:            []string{"--kube-context", "prod", "-n", "foo", "upgrade", "r", "c", "-f", "values.yaml"},
+			wantPositionals: []string{"upgrade", "r", "c"},
+			wantFlags: map[string][]string{
+				"kube-context": {"prod"},
+				"namespace":    {"foo"},
+				"values":       {"values.yaml"},
+			},
+		},
+		{
+			name:            "Value flag as last argument",
+			cmd:             "kubectl",
+			args:            []string{"get", "pods", "-n"},
+			wantPositionals: []string{"get", "pods"},
+			wantFlags:       map[string][]string{"namespace": {""}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ParseArgs(tc.cmd, tc.args)
+			if !reflect.DeepEqual(result.Positionals, tc.wantPositionals) {
+				t.Errorf("Expected positionals %v, got %v", tc.wantPositionals, result.Positionals)
+			}
+			if !reflect.DeepEqual(result.Flags, tc.wantFlags) {
+				t.Errorf("Expected flags %v, got %v", tc.wantFlags, result.Flags)
+			}
+		})
+	}
+}
+
+func TestParsedArgs_IsDryRun(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cmd      string
+		args     []string
+		expected bool
+	}{
+		{name: "kubectl apply", cmd: "kubectl", args: []string{"apply", "-f", "x.yaml"}, expected: false},
+		{name: "kubectl apply dry-run", cmd: "kubectl", args: []string{"apply", "-f", "x.yaml", "--dry-run=server"}, expected: true},
+		{name: "kubectl dry-run none", cmd: "kubectl", args: []string{"apply", "-f", "x.yaml", "--dry-run=none"}, expected: false},
+		{name: "kubectl dry-run 0", cmd: "kubectl", args: []string{"delete", "ns", "prod", "--dry-run=0"}, expected: false},
+		{name: "kubectl dry-run False", cmd: "kubectl", args: []string{"delete", "ns", "prod", "--dry-run=False"}, expected: false},
+		{name: "kubectl dry-run f", cmd: "kubectl", args: []string{"delete", "ns", "prod", "--dry-run=f"}, expected: false},
+		{name: "kubectl dry-run FALSE", cmd: "kubectl", args: []string{"delete", "ns", "prod", "--dry-run=FALSE"}, expected: false},
+		{name: "kubectl dry-run invalid value", cmd: "kubectl", args: []string{"delete", "ns", "prod", "--dry-run=maybe"}, expected: false},
+		{name: "kubectl dry-run 1", cmd: "kubectl", args: []string{"delete", "ns", "prod", "--dry-run=1"}, expected: true},
+		{name: "kubectl dry-run client", cmd: "kubectl", args: []string{"delete", "ns", "prod", "--dry-run=client"}, expected: true},
+		{name: "kubectl bare dry-run", cmd: "kubectl", args: []string{"delete", "ns", "prod", "--dry-run"}, expected: true},
+		{name: "kubectl diff", cmd: "kubectl", args: []string{"--context", "prod", "diff", "-f", "x.yaml"}, expected: true},
+		{name: "helm template", cmd: "helm", args: []string{"template", "foo", "./chart"}, expected: true},
+		{name: "helm upgrade dry-run", cmd: "helm", args: []string{"upgrade", "--dry-run", "foo", "./chart"}, expected: true},
+		{name: "helm diff plugin", cmd: "helm", args: []string{"diff", "upgrade", "foo", "./chart"}, expected: true},
+		{name: "helm upgrade", cmd: "helm", args: []string{"upgrade", "foo", "./chart"}, expected: false},
+		{name: "argocd app diff", cmd: "argocd", args: []string{"app", "diff", "foo"}, expected: true},
+		{name: "argocd app sync", cmd: "argocd", args: []string{"app", "sync", "foo"}, expected: false},
+		{name: "kubectl plugin dry-run", cmd: "kubectl", args: []string{"my-plugin", "--dry-run"}, expected: false},
+		{name: "kubectl rollout undo dry-run", cmd: "kubectl", args: []string{"rollout", "undo", "deploy/x", "--dry-run=client"}, expected: true},
+		{name: "kubectl rollout restart dry-run", cmd: "kubectl", args: []string{"rollout", "restart", "deploy/x", "--dry-run=client"}, expected: false},
+		{name: "helm plugin dry-run", cmd: "helm", args: []string{"my-plugin", "--dry-run"}, expected: false},
+		{name: "Unknown tool dry-run", cmd: "mytool", args: []string{"delete", "--dry-run"}, expected: false},
+		{name: "Unknown tool diff", cmd: "mytool", args: []string{"diff"}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := ParseArgs(tc.cmd, tc.args).IsDryRun(); result != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
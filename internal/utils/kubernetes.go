@@ -20,7 +20,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
@@ -28,10 +27,15 @@ import (
 	"k8s.io/client-go/util/homedir"
 )
 
-func getArgs(args []string, name string) string {
-	for i, arg := range args {
-		if arg == name {
-			return args[i+1]
+// Flags used by the supported tools to override the current context
+// (e.g. kubectl --context, helm --kube-context).
+var contextFlags = []string{"context", "kube-context"}
+
+// getFlag returns the last non-empty value of the first of the provided flags that is set.
+func getFlag(args *ParsedArgs, names ...string) string {
+	for _, name := range names {
+		if value, ok := args.GetFlag(name); ok && value != "" {
+			return value
 		}
 	}
 	return ""
@@ -40,6 +44,12 @@ func getArgs(args []string, name string) string {
 type NamespacedContext struct {
 	Namespace string
 	Context   string
+	// AllNamespaces is true if the command targets all the namespaces (e.g. kubectl -A).
+	AllNamespaces bool
+	// Server is the address of the cluster of the context, if defined in the kubeconfig.
+	Server string
+	// AuthInfo is the name of the kubeconfig user of the context, if defined in the kubeconfig.
+	AuthInfo string
 }
 
 func NewNamespacedContext(namespace, context string) *NamespacedContext {
@@ -49,43 +59,60 @@ func NewNamespacedContext(namespace, context string) *NamespacedContext {
 	}
 }
 
-func getKubeconfigPath() (string, error) {
-	kubeconfigPath := os.Getenv("KUBECONFIG")
-	if kubeconfigPath == "" {
-		home := homedir.HomeDir()
-		if home == "" {
-			return "", fmt.Errorf("could not find home directory")
-		}
-		kubeconfigPath = filepath.Join(home, ".kube", "config")
+// getKubeconfigPaths returns the kubeconfig files configured in the environment,
+// in order of precedence.
+func getKubeconfigPaths() ([]string, error) {
+	if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
+		// KUBECONFIG can contain multiple paths, which are merged like kubectl does
+		return filepath.SplitList(kubeconfig), nil
+	}
+	home := homedir.HomeDir()
+	if home == "" {
+		return nil, fmt.Errorf("could not find home directory")
+	}
+	return []string{filepath.Join(home, ".kube", "config")}, nil
+}
+
+// getLoadingRules returns the rules used to load the kubeconfig. If the explicit
+// path is not empty, only that file is loaded, otherwise all the files in the
+// environment are merged with the same precedence semantics used by kubectl.
+func getLoadingRules(explicitPath string) (*clientcmd.ClientConfigLoadingRules, error) {
+	if explicitPath != "" {
+		return &clientcmd.ClientConfigLoadingRules{ExplicitPath: explicitPath}, nil
 	}
-	// If KUBECONFIG contains multiple paths, use the first one.
-	parts := strings.Split(kubeconfigPath, ":")
-	return parts[0], nil
+	paths, err := getKubeconfigPaths()
+	if err != nil {
+		return nil, err
+	}
+	return &clientcmd.ClientConfigLoadingRules{Precedence: paths}, nil
 }
 
-func loadKubeconfig() (*clientcmdapi.Config, error) {
-	kubeconfigPath, err := getKubeconfigPath()
+func loadKubeconfig(explicitPath string) (*clientcmdapi.Config, error) {
+	rules, err := getLoadingRules(explicitPath)
 	if err != nil {
 		return nil, err
 	}
-	return clientcmd.LoadFromFile(kubeconfigPath)
+	return rules.Load()
 }
 
+// GetAvailableContexts returns the contexts defined in the kubeconfig files,
+// mapped to the path of the file each context comes from.
 func GetAvailableContexts() (map[string]string, error) {
-	config, err := loadKubeconfig()
+	config, err := loadKubeconfig("")
 	if err != nil {
 		return nil, err
 	}
 
 	var contexts = make(map[string]string, len(config.Contexts))
-	for context := range config.Contexts {
-		contexts[context] = context
+	for name, context := range config.Contexts {
+		contexts[name] = context.LocationOfOrigin
 	}
 	return contexts, nil
 }
 
-func GetNamespacedContext(cobraArgs []string) (*NamespacedContext, error) {
-	config, err := loadKubeconfig()
+func GetNamespacedContext(args *ParsedArgs) (*NamespacedContext, error) {
+	// The kubeconfig passed to the wrapped command takes precedence over the environment
+	config, err := loadKubeconfig(getFlag(args, "kubeconfig"))
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +120,7 @@ func GetNamespacedContext(cobraArgs []string) (*NamespacedContext, error) {
 	// First check if the context is passed as an argument.
 	// If not, get the current context from the kubeconfig.
 	var context string
-	contextArgs := getArgs(cobraArgs, "--context")
+	contextArgs := getFlag(args, contextFlags...)
 	if contextArgs != "" {
 		context = contextArgs
 	} else {
@@ -103,7 +130,7 @@ func GetNamespacedContext(cobraArgs []string) (*NamespacedContext, error) {
 	// First check if the namespace is passed as an argument.
 	// If not, get the current namespace from the current context.
 	var namespace = ""
-	namespaceArgs := getArgs(cobraArgs, "--namespace")
+	namespaceArgs := getFlag(args, "namespace")
 	if namespaceArgs != "" {
 		namespace = namespaceArgs
 	} else {
@@ -115,5 +142,15 @@ func GetNamespacedContext(cobraArgs []string) (*NamespacedContext, error) {
 		namespace = "default"
 	}
 
-	return NewNamespacedContext(namespace, context), nil
+	res := NewNamespacedContext(namespace, context)
+	if value, ok := args.GetFlag("all-namespaces"); ok && value != "false" {
+		res.AllNamespaces = true
+	}
+	if ctx, ok := config.Contexts[context]; ok {
+		res.AuthInfo = ctx.AuthInfo
+		if cluster, ok := config.Clusters[ctx.Cluster]; ok {
+			res.Server = cluster.Server
+		}
+	}
+	return res, nil
 }
@@ -17,43 +17,244 @@
 package utils
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
-func TestGetKubeconfigPath(t *testing.T) {
+func TestGetKubeconfigPaths(t *testing.T) {
 
 	t.Run("Test with KUBECONFIG set", func(t *testing.T) {
 		t.Setenv("KUBECONFIG", "/tmp/kubeconfig")
-		kubeconfigPath, err := getKubeconfigPath()
+		kubeconfigPaths, err := getKubeconfigPaths()
 		if err != nil {
-			t.Fatalf("Failed to get kubeconfig path: %v", err)
+			t.Fatalf("Failed to get kubeconfig paths: %v", err)
 		}
-		if kubeconfigPath != "/tmp/kubeconfig" {
-			t.Fatalf("Expected /tmp/kubeconfig, got %s", kubeconfigPath)
+		if !reflect.DeepEqual(kubeconfigPaths, []string{"/tmp/kubeconfig"}) {
+			t.Fatalf("Expected [/tmp/kubeconfig], got %v", kubeconfigPaths)
 		}
 	})
 
 	t.Run("Test with KUBECONFIG with multiple parts", func(t *testing.T) {
-		t.Setenv("KUBECONFIG", "/tmp/kubeconfig:/tmp/kubeconfig2")
-		kubeconfigPath, err := getKubeconfigPath()
+		t.Setenv("KUBECONFIG", "/tmp/kubeconfig"+string(os.PathListSeparator)+"/tmp/kubeconfig2")
+		kubeconfigPaths, err := getKubeconfigPaths()
 		if err != nil {
-			t.Fatalf("Failed to get kubeconfig path: %v", err)
+			t.Fatalf("Failed to get kubeconfig paths: %v", err)
 		}
-		if kubeconfigPath != "/tmp/kubeconfig" {
-			t.Fatalf("Expected /tmp/kubeconfig, got %s", kubeconfigPath)
+		expectedPaths := []string{"/tmp/kubeconfig", "/tmp/kubeconfig2"}
+		if !reflect.DeepEqual(kubeconfigPaths, expectedPaths) {
+			t.Fatalf("Expected %v, got %v", expectedPaths, kubeconfigPaths)
 		}
 	})
 
 	t.Run("Test with KUBECONFIG not set", func(t *testing.T) {
 		t.Setenv("HOME", "/tmp")
 		t.Setenv("KUBECONFIG", "")
-		kubeconfigPath, err := getKubeconfigPath()
+		kubeconfigPaths, err := getKubeconfigPaths()
 		if err != nil {
-			t.Fatalf("Failed to get kubeconfig path: %v", err)
+			t.Fatalf("Failed to get kubeconfig paths: %v", err)
 		}
-		expectedPath := "/tmp/.kube/config"
-		if kubeconfigPath != expectedPath {
-			t.Fatalf("Expected %s, got %s", expectedPath, kubeconfigPath)
+		expectedPaths := []string{"/tmp/.kube/config"}
+		if !reflect.DeepEqual(kubeconfigPaths, expectedPaths) {
+			t.Fatalf("Expected %v, got %v", expectedPaths, kubeconfigPaths)
+		}
+	})
+}
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+contexts:
+  - name: dev
+    context:
+      cluster: dev
+      user: dev
+  - name: prod
+    context:
+      cluster: prod
+      user: prod
+      namespace: payments
+clusters:
+  - name: dev
+    cluster:
+      server: https://dev.example.com
+  - name: prod
+    cluster:
+      server: https://prod.example.com
+users:
+  - name: dev
+    user: {}
+  - name: prod
+    user: {}
+`
+
+func writeTestKubeconfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+	return path
+}
+
+func TestGetNamespacedContext(t *testing.T) {
+	otherKubeconfig := writeTestKubeconfig(t, strings.ReplaceAll(testKubeconfig, "current-context: dev", "current-context: other"))
+	testCases := []struct {
+		name          string
+		cmd           string
+		args          []string
+		wantContext   string
+		wantNamespace string
+	}{
+		{
+			name:          "Current context",
+			cmd:           "kubectl",
+			args:          []string{"get", "pods"},
+			wantContext:   "dev",
+			wantNamespace: "default",
+		},
+		{
+			name:          "Context flag",
+			cmd:           "kubectl",
+			args:          []string{"--context", "prod", "get", "pods"},
+			wantContext:   "prod",
+			wantNamespace: "payments",
+		},
+		{
+			name:          "Context flag with equal sign",
+			cmd:           "kubectl",
+			args:          []string{"--context=prod", "get", "pods"},
+			wantContext:   "prod",
+			wantNamespace: "payments",
+		},
+		{
+			name:          "Helm kube-context",
+			cmd:           "helm",
+			args:          []string{"--kube-context=prod", "list"},
+			wantContext:   "prod",
+			wantNamespace: "payments",
+		},
+		{
+			name:          "Short namespace flag",
+			cmd:           "kubectl",
+			args:          []string{"-n", "foo", "get", "pods"},
+			wantContext:   "dev",
+			wantNamespace: "foo",
+		},
+		{
+			name:          "Attached short namespace flag",
+			cmd:           "kubectl",
+			args:          []string{"get", "pods", "-nfoo", "--context", "prod"},
+			wantContext:   "prod",
+			wantNamespace: "foo",
+		},
+		{
+			name:          "Namespace flag with equal sign",
+			cmd:           "helm",
+			args:          []string{"list", "--namespace=foo"},
+			wantContext:   "dev",
+			wantNamespace: "foo",
+		},
+		{
+			name:          "Flag as last argument",
+			cmd:           "kubectl",
+			args:          []string{"get", "pods", "--context"},
+			wantContext:   "dev",
+			wantNamespace: "default",
+		},
+		{
+			name:          "Kubeconfig flag",
+			cmd:           "kubectl",
+			args:          []string{"--kubeconfig", otherKubeconfig, "get", "pods"},
+			wantContext:   "other",
+			wantNamespace: "default",
+		},
+		{
+			name:          "Kubeconfig flag with equal sign",
+			cmd:           "helm",
+			args:          []string{"list", "--kubeconfig=" + otherKubeconfig},
+			wantContext:   "other",
+			wantNamespace: "default",
+		},
+	}
+
+	t.Setenv("KUBECONFIG", writeTestKubeconfig(t, testKubeconfig))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			namespacedContext, err := GetNamespacedContext(ParseArgs(tc.cmd, tc.args))
+			if err != nil {
+				t.Fatalf("Failed to get namespaced context: %v", err)
+			}
+			if namespacedContext.Context != tc.wantContext {
+				t.Errorf("Expected context %s, got %s", tc.wantContext, namespacedContext.Context)
+			}
+			if namespacedContext.Namespace != tc.wantNamespace {
+				t.Errorf("Expected namespace %s, got %s", tc.wantNamespace, namespacedContext.Namespace)
+			}
+		})
+	}
+}
+
+const testSecondKubeconfig = `apiVersion: v1
+kind: Config
+current-context: staging
+contexts:
+  - name: staging
+    context:
+      cluster: staging
+      user: staging
+      namespace: team-a
+  - name: prod
+    context:
+      cluster: staging
+      user: staging
+clusters:
+  - name: staging
+    cluster:
+      server: https://staging.example.com
+users:
+  - name: staging
+    user: {}
+`
+
+func TestMergedKubeconfig(t *testing.T) {
+	first := writeTestKubeconfig(t, testKubeconfig)
+	second := writeTestKubeconfig(t, testSecondKubeconfig)
+	t.Setenv("KUBECONFIG", first+string(os.PathListSeparator)+second)
+
+	t.Run("Available contexts come from all the files", func(t *testing.T) {
+		contexts, err := GetAvailableContexts()
+		if err != nil {
+			t.Fatalf("Failed to get available contexts: %v", err)
+		}
+		expected := map[string]string{
+			"dev":     first,
+			"prod":    first, // the first file wins
+			"staging": second,
+		}
+		if !reflect.DeepEqual(contexts, expected) {
+			t.Fatalf("Expected %v, got %v", expected, contexts)
+		}
+	})
+
+	t.Run("Context defined in the second file", func(t *testing.T) {
+		namespacedContext, err := GetNamespacedContext(ParseArgs("kubectl", []string{"--context", "staging", "get", "pods"}))
+		if err != nil {
+			t.Fatalf("Failed to get namespaced context: %v", err)
+		}
+		if namespacedContext.Context != "staging" || namespacedContext.Namespace != "team-a" {
+			t.Fatalf("Expected staging/team-a, got %s/%s", namespacedContext.Context, namespacedContext.Namespace)
+		}
+	})
+
+	t.Run("Current context from the first file", func(t *testing.T) {
+		namespacedContext, err := GetNamespacedContext(ParseArgs("kubectl", []string{"get", "pods"}))
+		if err != nil {
+			t.Fatalf("Failed to get namespaced context: %v", err)
+		}
+		if namespacedContext.Context != "dev" {
+			t.Fatalf("Expected dev, got %s", namespacedContext.Context)
 		}
 	})
 }
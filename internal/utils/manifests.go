@@ -0,0 +1,232 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Extensions of the files read by kubectl when a directory is passed with -f
+var manifestExtensions = []string{".json", ".yaml", ".yml"}
+
+// ManifestObject is a Kubernetes object defined in a manifest passed to the wrapped command.
+type ManifestObject struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+func (o ManifestObject) String() string {
+	if o.Namespace == "" {
+		return fmt.Sprintf("%s %s", o.Kind, o.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", o.Kind, o.Namespace, o.Name)
+}
+
+func (o ManifestObject) Ref() ResourceRef {
+	return ResourceRef{Kind: NormalizeResourceKind(o.Kind), Name: o.Name}
+}
+
+type manifest struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Items []manifest `json:"items"`
+}
+
+func (m manifest) objects() []ManifestObject {
+	// Lists (e.g. the output of "kubectl get -o yaml") contain the actual objects as items
+	if len(m.Items) > 0 {
+		res := make([]ManifestObject, 0, len(m.Items))
+		for _, item := range m.Items {
+			res = append(res, item.objects()...)
+		}
+		return res
+	}
+	if m.Kind == "" {
+		return nil
+	}
+	return []ManifestObject{{Kind: m.Kind, Name: m.Metadata.Name, Namespace: m.Metadata.Namespace}}
+}
+
+// ParseManifests returns the objects defined in the provided YAML or JSON stream,
+// which can contain multiple documents.
+func ParseManifests(r io.Reader) ([]ManifestObject, error) {
+	res := make([]ManifestObject, 0)
+	decoder := k8syaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var m manifest
+		err := decoder.Decode(&m)
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, m.objects()...)
+	}
+}
+
+// HasManifests returns true if manifests are passed to the command with -f or -k.
+func (p *ParsedArgs) HasManifests() bool {
+	if p.Tool != "kubectl" {
+		return false
+	}
+	return p.HasFlag("filename") || p.HasFlag("kustomize")
+}
+
+// ReadsStdin returns true if the command reads a manifest from stdin (e.g. "kubectl apply -f -").
+func (p *ParsedArgs) ReadsStdin() bool {
+	if p.Tool != "kubectl" {
+		return false
+	}
+	for _, filename := range p.Flags["filename"] {
+		if filename == "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRemoteManifests returns true if manifests are passed to the command as URLs, which are not inspected.
+func (p *ParsedArgs) HasRemoteManifests() bool {
+	return slices.ContainsFunc(p.Flags["filename"], isURL)
+}
+
+func isURL(filename string) bool {
+	return strings.HasPrefix(filename, "http://") || strings.HasPrefix(filename, "https://")
+}
+
+// LoadManifests returns the objects defined in the files, directories and
+// kustomization directories passed to the command, and in stdin if the command
+// reads from it. URLs are not inspected.
+func (p *ParsedArgs) LoadManifests(stdin []byte) ([]ManifestObject, error) {
+	res := make([]ManifestObject, 0)
+	if !p.HasManifests() {
+		return res, nil
+	}
+	recursive := false
+	if value, ok := p.GetFlag("recursive"); ok && value != "false" {
+		recursive = true
+	}
+	for _, filename := range p.Flags["filename"] {
+		var (
+			objects []ManifestObject
+			err     error
+		)
+		switch {
+		case filename == "-":
+			objects, err = ParseManifests(bytes.NewReader(stdin))
+		case isURL(filename):
+			continue
+		default:
+			objects, err = loadManifestPath(filename, recursive)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading manifests %q: %w", filename, err)
+		}
+		res = append(res, objects...)
+	}
+	for _, dir := range p.Flags["kustomize"] {
+		objects, err := p.loadKustomization(dir)
+		if err != nil {
+			return nil, fmt.Errorf("error building kustomization %q: %w", dir, err)
+		}
+		res = append(res, objects...)
+	}
+	return res, nil
+}
+
+func loadManifestPath(path string, recursive bool) ([]ManifestObject, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadManifestFile(path)
+	}
+	res := make([]ManifestObject, 0)
+	err = filepath.WalkDir(path, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if filePath != path && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isManifestFile(filePath) {
+			return nil
+		}
+		objects, err := loadManifestFile(filePath)
+		if err != nil {
+			return err
+		}
+		res = append(res, objects...)
+		return nil
+	})
+	return res, err
+}
+
+func loadManifestFile(path string) ([]ManifestObject, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return ParseManifests(f)
+}
+
+// loadKustomization builds the kustomization with the wrapped tool (e.g. "oc kustomize"),
+// or with "kustomize build" if the wrapped tool is not found.
+func (p *ParsedArgs) loadKustomization(dir string) ([]ManifestObject, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("kustomize", "build", dir)
+	if _, err := exec.LookPath(p.Command); err == nil {
+		cmd = exec.Command(p.Command, "kustomize", dir)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return ParseManifests(&stdout)
+}
+
+func isManifestFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range manifestExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
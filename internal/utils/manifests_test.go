@@ -0,0 +1,154 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testManifests = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: payments
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: payments
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: config
+`
+
+func TestParseManifests(t *testing.T) {
+	t.Run("YAML", func(t *testing.T) {
+		objects, err := ParseManifests(strings.NewReader(testManifests))
+		if err != nil {
+			t.Fatalf("Failed to parse manifests: %v", err)
+		}
+		expected := []ManifestObject{
+			{Kind: "Namespace", Name: "payments"},
+			{Kind: "Deployment", Name: "api", Namespace: "payments"},
+			{Kind: "ConfigMap", Name: "config"},
+		}
+		if !reflect.DeepEqual(objects, expected) {
+			t.Errorf("Expected %v, got %v", expected, objects)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		objects, err := ParseManifests(strings.NewReader(`{"kind": "Secret", "metadata": {"name": "a", "namespace": "b"}}`))
+		if err != nil {
+			t.Fatalf("Failed to parse manifests: %v", err)
+		}
+		expected := []ManifestObject{{Kind: "Secret", Name: "a", Namespace: "b"}}
+		if !reflect.DeepEqual(objects, expected) {
+			t.Errorf("Expected %v, got %v", expected, objects)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := ParseManifests(strings.NewReader("kind: [")); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
+
+func TestParsedArgs_LoadManifests(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(path, content string) {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	writeFile("ns.yaml", "kind: Namespace\nmetadata:\n  name: a\n")
+	writeFile("README.md", "not a manifest")
+	writeFile("nested/pvc.yml", "kind: PersistentVolumeClaim\nmetadata:\n  name: b\n")
+
+	testCases := []struct {
+		name     string
+		args     []string
+		stdin    string
+		expected []string
+	}{
+		{
+			name:     "File",
+			args:     []string{"apply", "-f", filepath.Join(dir, "ns.yaml")},
+			expected: []string{"Namespace a"},
+		},
+		{
+			name:     "Directory",
+			args:     []string{"apply", "-f", dir},
+			expected: []string{"Namespace a"},
+		},
+		{
+			name:     "Recursive directory",
+			args:     []string{"apply", "-f", dir, "-R"},
+			expected: []string{"PersistentVolumeClaim b", "Namespace a"},
+		},
+		{
+			name:     "Stdin",
+			args:     []string{"apply", "-f", "-"},
+			stdin:    "kind: Secret\nmetadata:\n  name: c\n  namespace: d\n",
+			expected: []string{"Secret d/c"},
+		},
+		{
+			name:     "URLs are skipped",
+			args:     []string{"apply", "-f", "https://example.com/x.yaml"},
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects, err := ParseArgs("kubectl", tc.args).LoadManifests([]byte(tc.stdin))
+			if err != nil {
+				t.Fatalf("Failed to load manifests: %v", err)
+			}
+			result := make([]string, 0)
+			for _, object := range objects {
+				result = append(result, object.String())
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestParsedArgs_HasRemoteManifests(t *testing.T) {
+	if !ParseArgs("kubectl", []string{"apply", "-f", "x.yaml", "-f", "https://example.com/x.yaml"}).HasRemoteManifests() {
+		t.Errorf("Expected remote manifests")
+	}
+	if ParseArgs("kubectl", []string{"apply", "-f", "x.yaml"}).HasRemoteManifests() {
+		t.Errorf("Expected no remote manifests")
+	}
+}
@@ -17,7 +17,12 @@
 package utils
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
 	"strings"
 
 	"github.com/charmbracelet/huh"
@@ -54,19 +59,107 @@ func PrintWarning(msg string) error {
 	return err
 }
 
-func Confirm(message string) (bool, error) {
+// ErrNoTerminal is returned when a prompt is required but there is no terminal to ask the user.
+var ErrNoTerminal = errors.New("no terminal available")
+
+type terminal struct {
+	in  *os.File
+	out *os.File
+}
+
+// openTerminal opens the controlling terminal, so that prompts work
+// even when stdin is used for something else (e.g. "kubectl apply -f -").
+func openTerminal() (*terminal, error) {
+	in, err := os.OpenFile(terminalInputPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoTerminal, err)
+	}
+	if terminalOutputPath == terminalInputPath {
+		return &terminal{in: in, out: in}, nil
+	}
+	out, err := os.OpenFile(terminalOutputPath, os.O_RDWR, 0)
+	if err != nil {
+		_ = in.Close()
+		return nil, fmt.Errorf("%w: %v", ErrNoTerminal, err)
+	}
+	return &terminal{in: in, out: out}, nil
+}
+
+func (t *terminal) Close() {
+	_ = t.in.Close()
+	if t.out != t.in {
+		_ = t.out.Close()
+	}
+}
+
+// readFromTerminal shows the provided prompt on the terminal and returns the line typed by the user.
+func readFromTerminal(prompt string) (string, error) {
+	t, err := openTerminal()
+	if err != nil {
+		return "", err
+	}
+	defer t.Close()
+
 	c := color.New(color.FgYellow)
-	_, err := c.Printf("%s (y/n): ", message)
+	_, err = c.Fprint(t.out, prompt)
 	if err != nil {
-		return false, err
+		return "", err
+	}
+	input, err := bufio.NewReader(t.in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
 	}
+	return strings.TrimSpace(input), nil
+}
 
-	var input string
-	_, err = fmt.Scanln(&input)
+// Confirm asks the user for confirmation on the terminal. It returns
+// ErrNoTerminal if there is no terminal available.
+func Confirm(message string) (bool, error) {
+	input, err := readFromTerminal(fmt.Sprintf("%s (y/n): ", message))
 	if err != nil {
 		return false, err
 	}
-	input = strings.TrimSpace(strings.ToLower(input))
+	return strings.ToLower(input) == "y", nil
+}
+
+// ConfirmChallenge asks the user to confirm by typing the expected value on the terminal,
+// allowing up to the provided number of wrong answers. The comparison is case-sensitive,
+// so that confirming doesn't become a reflex. It returns ErrNoTerminal if there is no
+// terminal available.
+func ConfirmChallenge(message string, expected string, retries int) (bool, error) {
+	prompt := fmt.Sprintf("%s Type %q to confirm: ", message, expected)
+	for attempt := 0; attempt <= retries; attempt++ {
+		input, err := readFromTerminal(prompt)
+		if err != nil {
+			return false, err
+		}
+		if input == expected {
+			return true, nil
+		}
+		if input == "" {
+			return false, nil
+		}
+		if left := retries - attempt; left > 0 {
+			prompt = fmt.Sprintf("Wrong answer (%d attempts left). Type %q to confirm: ", left, expected)
+		}
+	}
+	return false, nil
+}
 
-	return input == "y", nil
+const (
+	randomWordConsonants = "bcdfghjklmnprstvz"
+	randomWordVowels     = "aeiou"
+)
+
+// RandomWord returns a pronounceable random lowercase word of the provided length.
+func RandomWord(length int) string {
+	var sb strings.Builder
+	for i := 0; i < length; i++ {
+		letters := randomWordConsonants
+		if i%2 == 1 {
+			letters = randomWordVowels
+		}
+		sb.WriteByte(letters[rand.IntN(len(letters))])
+	}
+	return sb.String()
 }
@@ -0,0 +1,34 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRandomWord(t *testing.T) {
+	for _, length := range []int{1, 6, 10} {
+		word := RandomWord(length)
+		if len(word) != length {
+			t.Errorf("Expected a word of length %d, got %q", length, word)
+		}
+		if strings.ToLower(word) != word {
+			t.Errorf("Expected a lowercase word, got %q", word)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import "strings"
+
+// ALL_RESOURCES is the kind used by "kubectl <verb> all", which targets every resource kind.
+const ALL_RESOURCES = "all"
+
+// Short names and plurals of the built-in resource kinds, mapped to their singular name.
+var resourceKindAliases = map[string]string{
+	"po":                                "pod",
+	"pods":                              "pod",
+	"svc":                               "service",
+	"services":                          "service",
+	"deploy":                            "deployment",
+	"deployments":                       "deployment",
+	"rs":                                "replicaset",
+	"replicasets":                       "replicaset",
+	"rc":                                "replicationcontroller",
+	"replicationcontrollers":            "replicationcontroller",
+	"sts":                               "statefulset",
+	"statefulsets":                      "statefulset",
+	"ds":                                "daemonset",
+	"daemonsets":                        "daemonset",
+	"jobs":                              "job",
+	"cj":                                "cronjob",
+	"cronjobs":                          "cronjob",
+	"ns":                                "namespace",
+	"namespaces":                        "namespace",
+	"no":                                "node",
+	"nodes":                             "node",
+	"pvc":                               "persistentvolumeclaim",
+	"persistentvolumeclaims":            "persistentvolumeclaim",
+	"pv":                                "persistentvolume",
+	"persistentvolumes":                 "persistentvolume",
+	"cm":                                "configmap",
+	"configmaps":                        "configmap",
+	"secrets":                           "secret",
+	"sa":                                "serviceaccount",
+	"serviceaccounts":                   "serviceaccount",
+	"ing":                               "ingress",
+	"ingresses":                         "ingress",
+	"crd":                               "customresourcedefinition",
+	"crds":                              "customresourcedefinition",
+	"customresourcedefinitions":         "customresourcedefinition",
+	"hpa":                               "horizontalpodautoscaler",
+	"horizontalpodautoscalers":          "horizontalpodautoscaler",
+	"pdb":                               "poddisruptionbudget",
+	"poddisruptionbudgets":              "poddisruptionbudget",
+	"netpol":                            "networkpolicy",
+	"networkpolicies":                   "networkpolicy",
+	"sc":                                "storageclass",
+	"storageclasses":                    "storageclass",
+	"roles":                             "role",
+	"rolebindings":                      "rolebinding",
+	"clusterroles":                      "clusterrole",
+	"clusterrolebindings":               "clusterrolebinding",
+	"ep":                                "endpoints",
+	"ev":                                "event",
+	"events":                            "event",
+	"limits":                            "limitrange",
+	"limitranges":                       "limitrange",
+	"quota":                             "resourcequota",
+	"resourcequotas":                    "resourcequota",
+	"pc":                                "priorityclass",
+	"priorityclasses":                   "priorityclass",
+	"csr":                               "certificatesigningrequest",
+	"certificatesigningrequests":        "certificatesigningrequest",
+	"mutatingwebhookconfigurations":     "mutatingwebhookconfiguration",
+	"validatingwebhookconfigurations":   "validatingwebhookconfiguration",
+	"apiservices":                       "apiservice",
+	"leases":                            "lease",
+	"ingressclasses":                    "ingressclass",
+	"runtimeclasses":                    "runtimeclass",
+	"volumeattachments":                 "volumeattachment",
+	"csidrivers":                        "csidriver",
+	"csinodes":                          "csinode",
+	"controllerrevisions":               "controllerrevision",
+	"endpointslices":                    "endpointslice",
+	"podtemplates":                      "podtemplate",
+	"validatingadmissionpolicies":       "validatingadmissionpolicy",
+	"validatingadmissionpolicybindings": "validatingadmissionpolicybinding",
+}
+
+// Built-in resource kinds that don't belong to a namespace.
+var clusterScopedKinds = map[string]struct{}{
+	"apiservice":                       {},
+	"certificatesigningrequest":        {},
+	"clusterrole":                      {},
+	"clusterrolebinding":               {},
+	"componentstatus":                  {},
+	"csidriver":                        {},
+	"csinode":                          {},
+	"customresourcedefinition":         {},
+	"ingressclass":                     {},
+	"mutatingwebhookconfiguration":     {},
+	"namespace":                        {},
+	"node":                             {},
+	"persistentvolume":                 {},
+	"priorityclass":                    {},
+	"runtimeclass":                     {},
+	"storageclass":                     {},
+	"validatingadmissionpolicy":        {},
+	"validatingadmissionpolicybinding": {},
+	"validatingwebhookconfiguration":   {},
+	"volumeattachment":                 {},
+}
+
+// IsClusterScoped returns true if the resources of the provided normalized kind don't belong to a namespace.
+func IsClusterScoped(kind string) bool {
+	_, ok := clusterScopedKinds[kind]
+	return ok
+}
+
+// ResourceRef is a reference to a Kubernetes resource targeted by a command.
+type ResourceRef struct {
+	// Kind is the normalized kind of the resource (see NormalizeResourceKind).
+	Kind string
+	// Name is the name of the resource, or an empty string if the command
+	// targets all the resources of the kind (e.g. "kubectl delete pods --all").
+	Name string
+}
+
+// NormalizeResourceKind converts the provided resource kind to its lower-case
+// singular form, resolving short names and plurals and dropping the API group
+// (e.g. "deploy", "Deployments" and "deployments.apps" all become "deployment").
+func NormalizeResourceKind(kind string) string {
+	kind = strings.ToLower(kind)
+	kind, _, _ = strings.Cut(kind, ".")
+	if alias, ok := resourceKindAliases[kind]; ok {
+		return alias
+	}
+	return kind
+}
+
+// ParseResourceRefs returns the resources referenced by the provided
+// arguments, supporting both the "kind name..." and the "kind/name..." forms.
+// Multiple kinds can be provided as a comma-separated list (e.g. "pod,svc").
+func ParseResourceRefs(args []string) []ResourceRef {
+	refs := make([]ResourceRef, 0)
+	if len(args) == 0 {
+		return refs
+	}
+	// kind/name form
+	if strings.Contains(args[0], "/") {
+		for _, arg := range args {
+			kind, name, ok := strings.Cut(arg, "/")
+			if !ok {
+				continue
+			}
+			refs = append(refs, ResourceRef{Kind: NormalizeResourceKind(kind), Name: name})
+		}
+		return refs
+	}
+	// kind name form
+	for _, kind := range strings.Split(args[0], ",") {
+		if kind == "" {
+			continue
+		}
+		kind = NormalizeResourceKind(kind)
+		if len(args) == 1 {
+			refs = append(refs, ResourceRef{Kind: kind})
+			continue
+		}
+		for _, name := range args[1:] {
+			refs = append(refs, ResourceRef{Kind: kind, Name: name})
+		}
+	}
+	return refs
+}
+
+// Resources returns the resources targeted by the command, skipping the
+// first commandLen positional arguments (e.g. 1 for "delete", 2 for "rollout restart").
+// The objects defined in the manifests of the command are included as well.
+func (p *ParsedArgs) Resources(commandLen int) []ResourceRef {
+	res := make([]ResourceRef, 0)
+	if commandLen <= len(p.Positionals) {
+		res = ParseResourceRefs(p.Positionals[commandLen:])
+	}
+	for _, object := range p.Manifests {
+		res = append(res, object.Ref())
+	}
+	return res
+}
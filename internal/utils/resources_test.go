@@ -0,0 +1,65 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseResourceRefs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected []ResourceRef
+	}{
+		{
+			name:     "No args",
+			args:     []string{},
+			expected: []ResourceRef{},
+		},
+		{
+			name:     "Kind only",
+			args:     []string{"pods"},
+			expected: []ResourceRef{{Kind: "pod"}},
+		},
+		{
+			name:     "Kind and names",
+			args:     []string{"deploy", "a", "b"},
+			expected: []ResourceRef{{Kind: "deployment", Name: "a"}, {Kind: "deployment", Name: "b"}},
+		},
+		{
+			name:     "Multiple kinds",
+			args:     []string{"svc,ns", "a"},
+			expected: []ResourceRef{{Kind: "service", Name: "a"}, {Kind: "namespace", Name: "a"}},
+		},
+		{
+			name:     "Kind/name form",
+			args:     []string{"deployments.apps/a", "Pod/b"},
+			expected: []ResourceRef{{Kind: "deployment", Name: "a"}, {Kind: "pod", Name: "b"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ParseResourceRefs(tc.args)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
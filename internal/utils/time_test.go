@@ -0,0 +1,86 @@
+/*
+ * Copyright 2025 Michele Zanotti <m.zanotti019@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		value    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "Duration",
+			value:    "90m",
+			expected: time.Date(2025, 1, 31, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "Days",
+			value:    "7d",
+			expected: time.Date(2025, 1, 24, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Date",
+			value:    "2025-01-30",
+			expected: time.Date(2025, 1, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Date and time",
+			value:    "2025-01-30 08:15",
+			expected: time.Date(2025, 1, 30, 8, 15, 0, 0, time.UTC),
+		},
+		{
+			name:     "RFC3339",
+			value:    "2025-01-30T08:15:00+01:00",
+			expected: time.Date(2025, 1, 30, 7, 15, 0, 0, time.UTC),
+		},
+		{
+			name:    "Negative duration",
+			value:   "-1h",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid time",
+			value:   "yesterday",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := ParseTime(tc.value, now)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", res)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !res.Equal(tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, res)
+			}
+		})
+	}
+}
@@ -26,6 +26,7 @@ func main() {
 	rootCmd := cmd.NewRootCmd()
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		cmd.PrintError(err)
+		os.Exit(cmd.ExitCode(err))
 	}
 }